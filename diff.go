@@ -0,0 +1,141 @@
+// diff.go
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/Viktor45/dat2json/internal/geoip"
+	"github.com/Viktor45/dat2json/internal/geosite"
+	"github.com/Viktor45/dat2json/pkg/format"
+)
+
+// diffReport is the machine-readable result of "dat2json diff".
+type diffReport struct {
+	AddedTags   []string             `json:"added_tags,omitempty" yaml:"added_tags,omitempty"`
+	RemovedTags []string             `json:"removed_tags,omitempty" yaml:"removed_tags,omitempty"`
+	Changed     map[string]entryDiff `json:"changed,omitempty" yaml:"changed,omitempty"`
+}
+
+// entryDiff lists the CIDRs or rules added to and removed from a single tag.
+type entryDiff struct {
+	Added   []string `json:"added,omitempty" yaml:"added,omitempty"`
+	Removed []string `json:"removed,omitempty" yaml:"removed,omitempty"`
+}
+
+// runDiff implements "dat2json diff": it decodes two inputs and reports added and
+// removed tags and, per tag, added and removed CIDRs or domain rules.
+func runDiff(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	ip := fs.Bool("ip", false, "Treat inputs as geoip.dat")
+	site := fs.Bool("site", false, "Treat inputs as geosite.dat")
+	outFormat := fs.String("format", "text", "Output format: text, json or yaml")
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(fs.Output(), "Usage: %s diff --ip|--site [--format text|json|yaml] OLD NEW\n", os.Args[0])
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *ip == *site {
+		return fmt.Errorf("must specify exactly one of --ip or --site")
+	}
+	if fs.NArg() != 2 {
+		return fmt.Errorf("diff requires exactly two input files")
+	}
+	if *outFormat != "text" && *outFormat != "json" && *outFormat != "yaml" {
+		return fmt.Errorf("--format must be 'text', 'json' or 'yaml'")
+	}
+
+	older, err := loadInput(fs.Arg(0), *site)
+	if err != nil {
+		return err
+	}
+	newer, err := loadInput(fs.Arg(1), *site)
+	if err != nil {
+		return err
+	}
+	report, err := diffData(older, newer, *site)
+	if err != nil {
+		return err
+	}
+
+	if *outFormat == "text" {
+		_, err := io.WriteString(stdout, formatDiffText(report))
+		return err
+	}
+	out, err := format.Marshal(report, *outFormat)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(stdout, string(out))
+	return err
+}
+
+// diffData compares two decoded inputs. GeoIP CIDRs are compared by address space,
+// geosite rules by their exact text.
+func diffData(older, newer map[string][]string, isGeoSite bool) (*diffReport, error) {
+	report := &diffReport{Changed: make(map[string]entryDiff)}
+	for tag := range newer {
+		if _, ok := older[tag]; !ok {
+			report.AddedTags = append(report.AddedTags, tag)
+		}
+	}
+	for tag, oldValues := range older {
+		newValues, ok := newer[tag]
+		if !ok {
+			report.RemovedTags = append(report.RemovedTags, tag)
+			continue
+		}
+		var d entryDiff
+		if isGeoSite {
+			d.Added, d.Removed = geosite.Diff(oldValues, newValues)
+		} else {
+			var err error
+			d.Added, d.Removed, err = geoip.Diff(oldValues, newValues)
+			if err != nil {
+				return nil, fmt.Errorf("country %s: %w", tag, err)
+			}
+		}
+		if len(d.Added) > 0 || len(d.Removed) > 0 {
+			report.Changed[tag] = d
+		}
+	}
+	sort.Strings(report.AddedTags)
+	sort.Strings(report.RemovedTags)
+	return report, nil
+}
+
+// formatDiffText renders a report for humans: "+"/"-" lines for tags, and "~" blocks
+// with indented "+"/"-" lines for changed tags.
+func formatDiffText(report *diffReport) string {
+	var b strings.Builder
+	for _, tag := range report.AddedTags {
+		fmt.Fprintf(&b, "+ %s\n", tag)
+	}
+	for _, tag := range report.RemovedTags {
+		fmt.Fprintf(&b, "- %s\n", tag)
+	}
+	tags := make([]string, 0, len(report.Changed))
+	for tag := range report.Changed {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	for _, tag := range tags {
+		d := report.Changed[tag]
+		fmt.Fprintf(&b, "~ %s (+%d -%d)\n", tag, len(d.Added), len(d.Removed))
+		for _, v := range d.Added {
+			fmt.Fprintf(&b, "  + %s\n", v)
+		}
+		for _, v := range d.Removed {
+			fmt.Fprintf(&b, "  - %s\n", v)
+		}
+	}
+	if b.Len() == 0 {
+		return "no differences\n"
+	}
+	return b.String()
+}
@@ -0,0 +1,70 @@
+// diff_test.go
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunDiffGeoIP(t *testing.T) {
+	dir := t.TempDir()
+	oldFile := filepath.Join(dir, "old.json")
+	newFile := filepath.Join(dir, "new.json")
+	if err := os.WriteFile(oldFile, []byte(`{"US": ["10.0.0.0/23", "192.168.0.0/24"], "RU": ["5.0.0.0/8"]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(newFile, []byte(`{"US": ["10.0.0.0/24", "10.0.1.0/24"], "DE": ["6.0.0.0/8"]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := runDiff([]string{"--ip", oldFile, newFile}, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "+ DE\n- RU\n~ US (+0 -1)\n  - 192.168.0.0/24\n"
+	if out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+}
+
+func TestRunDiffGeoSiteJSON(t *testing.T) {
+	dir := t.TempDir()
+	oldFile := filepath.Join(dir, "old.yaml")
+	newFile := filepath.Join(dir, "new.yaml")
+	if err := os.WriteFile(oldFile, []byte("google:\n  - domain:google.com\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(newFile, []byte("google:\n  - domain:google.com\n  - full:www.google.cn\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := runDiff([]string{"--site", "--format", "json", oldFile, newFile}, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), `"added": [`) || !strings.Contains(out.String(), `"full:www.google.cn"`) {
+		t.Errorf("unexpected output: %s", out.String())
+	}
+}
+
+func TestRunDiffIdentical(t *testing.T) {
+	var out bytes.Buffer
+	if err := runDiff([]string{"--ip", "example/zkeen-ip.dat", "example/zkeen-ip.dat"}, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != "no differences\n" {
+		t.Errorf("unexpected output: %q", out.String())
+	}
+}
+
+func TestRunDiffArgs(t *testing.T) {
+	if err := runDiff([]string{"--ip", "only-one.dat"}, &bytes.Buffer{}); err == nil {
+		t.Fatal("expected error")
+	}
+	if err := runDiff([]string{"a.dat", "b.dat"}, &bytes.Buffer{}); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -0,0 +1,64 @@
+// expr.go
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Viktor45/dat2json/internal/expr"
+	"github.com/Viktor45/dat2json/internal/geoip"
+	"github.com/Viktor45/dat2json/internal/geosite"
+)
+
+// exprQuery is a parsed --expr expression together with the name of the synthetic
+// entry it produces.
+type exprQuery struct {
+	node      expr.Node
+	name      string
+	tags      []string
+	isGeoSite bool
+}
+
+// parseExprQuery parses a set expression over the tags or country codes of the input.
+// Country codes of the synthetic geoip entry are upper-cased like the others.
+func parseExprQuery(expression, name string, isGeoSite bool) (*exprQuery, error) {
+	node, err := expr.Parse(expression)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		return nil, fmt.Errorf("--expr-tag must not be empty")
+	}
+	if !isGeoSite {
+		name = strings.ToUpper(name)
+	}
+	return &exprQuery{node: node, name: name, tags: expr.Tags(node), isGeoSite: isGeoSite}, nil
+}
+
+// wants reports whether an input entry is referenced by the expression, so only
+// those entries need to be decoded.
+func (q *exprQuery) wants(key string) bool {
+	for _, t := range q.tags {
+		if strings.EqualFold(t, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// eval computes the expression over the decoded entries and returns a result
+// holding only the synthetic entry, along with notes on geosite rules that could
+// not be combined precisely.
+func (q *exprQuery) eval(data map[string][]string) (map[string][]string, []string, error) {
+	var values, notes []string
+	var err error
+	if q.isGeoSite {
+		values, notes, err = geosite.Eval(q.node, data)
+	} else {
+		values, err = geoip.Eval(q.node, data)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("evaluate --expr: %w", err)
+	}
+	return map[string][]string{q.name: values}, notes, nil
+}
@@ -0,0 +1,82 @@
+// expr_test.go
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/Viktor45/dat2json/pkg/format"
+)
+
+func TestExprQuery(t *testing.T) {
+	q, err := parseExprQuery("cn - PRIVATE", "not-private", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !q.wants("CN") || !q.wants("private") || q.wants("RU") {
+		t.Errorf("unexpected wants for tags %v", q.tags)
+	}
+	got, _, err := q.eval(map[string][]string{
+		"CN":      {"10.0.0.0/15"},
+		"PRIVATE": {"10.1.0.0/16"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string][]string{"NOT-PRIVATE": {"10.0.0.0/16"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected result: %v", got)
+	}
+}
+
+func TestParseExprQueryErrors(t *testing.T) {
+	if _, err := parseExprQuery("CN |", "expr", false); err == nil {
+		t.Error("expected syntax error")
+	}
+	if _, err := parseExprQuery("CN", "", false); err == nil {
+		t.Error("expected error for empty name")
+	}
+}
+
+func TestIntegrationExpr(t *testing.T) {
+	resetFlags()
+	outputFile := filepath.Join(t.TempDir(), "out.json")
+
+	os.Args = []string{"dat2json", "-i", "example/zkeen-ip.dat", "--ip", "-o", outputFile, "--expr", "TELEGRAM - TELEGRAM", "--expr-tag", "empty"}
+	main()
+
+	content, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := format.Deserialize(content, "json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if values, ok := result["EMPTY"]; !ok || len(values) != 0 {
+		t.Errorf("unexpected output: %s", string(content))
+	}
+}
+
+func TestExprQueryGeoSite(t *testing.T) {
+	q, err := parseExprQuery("all - google", "not-google", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, notes, err := q.eval(map[string][]string{
+		"ALL":    {"full:mail.google.com", "domain:github.com", "regexp:^ads\\."},
+		"GOOGLE": {"domain:google.com"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string][]string{"not-google": {"domain:github.com", "regexp:^ads\\."}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected result: %v", got)
+	}
+	if len(notes) != 1 {
+		t.Errorf("expected a note for the regexp rule, got %v", notes)
+	}
+}
@@ -0,0 +1,312 @@
+// filter.go
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Viktor45/dat2json/internal/geoip"
+	"github.com/Viktor45/dat2json/internal/geosite"
+)
+
+// entryFilter selects and transforms decoded entries according to the command-line filters.
+// It handles one entry at a time so the in-memory and streaming export paths share it.
+type entryFilter struct {
+	isGeoSite  bool
+	sortValues bool
+	aggregate  bool
+	canonical  bool
+	prune      bool
+	family     string
+	tags       []string
+	countries  []string
+	found      map[string]bool
+	warnings   []string
+	err        error
+
+	// CIDR counts before and after aggregation.
+	cidrsIn, cidrsOut int
+	// CIDRs with host bits set, masked when canonicalizing.
+	nonCanonical int
+	// CIDR counts seen and kept by the address family filter.
+	familySeen, familyKept int
+	// Rules removed by pruning, as "TAG: RULE (covered by RULE)".
+	pruned []string
+}
+
+// maxListed limits how many individual items a report lists before summarizing the rest.
+const maxListed = 10
+
+func newEntryFilter(isGeoSite bool) *entryFilter {
+	f := &entryFilter{
+		isGeoSite: isGeoSite,
+		family:    geoip.FamilyBoth,
+		found:     make(map[string]bool),
+	}
+	if isGeoSite {
+		if *countryFilter != "" {
+			f.warn("--country is ignored for geosite.dat")
+		}
+		f.tags = uniqueStrings(parseList(*tagFilter, false))
+		f.sortValues = *sortKeys
+		f.prune = *pruneFlag
+	} else {
+		if *tagFilter != "" {
+			f.warn("--tag is ignored for geoip.dat")
+		}
+		f.countries = uniqueStrings(parseList(*countryFilter, true))
+		f.aggregate = *aggregate
+		f.canonical = *canonical
+		f.family = *familyFlag
+	}
+	if f.isGeoSite {
+		if *aggregate {
+			f.warn("--aggregate is ignored for geosite.dat")
+		}
+		if *canonical {
+			f.warn("--canonical is ignored for geosite.dat")
+		}
+		if *familyFlag != geoip.FamilyBoth {
+			f.warn("--family is ignored for geosite.dat")
+		}
+		if *splitFamily {
+			f.warn("--split-family is ignored for geosite.dat")
+		}
+	} else if *pruneFlag {
+		f.warn("--prune is ignored for geoip.dat (use --aggregate)")
+	}
+	return f
+}
+
+func (f *entryFilter) warn(format string, args ...any) {
+	f.warnings = append(f.warnings, fmt.Sprintf(format, args...))
+}
+
+// active reports whether a tag or country filter was requested.
+func (f *entryFilter) active() bool {
+	return len(f.tags) > 0 || len(f.countries) > 0
+}
+
+// wants reports whether an entry with the given key can produce output, so callers
+// can skip decoding entries that the filters would drop anyway.
+func (f *entryFilter) wants(key string) bool {
+	if !f.active() {
+		return true
+	}
+	if f.isGeoSite {
+		lower := strings.ToLower(key)
+		for _, t := range f.tags {
+			if name, _ := parseTagSelector(t); name == lower {
+				return true
+			}
+		}
+		return false
+	}
+	code := strings.TrimPrefix(key, geoip.ReverseMatchPrefix)
+	for _, c := range f.countries {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// apply passes a decoded entry through the filters and calls emit for every selected output entry.
+func (f *entryFilter) apply(key string, values []string, emit func(string, []string)) {
+	if f.isGeoSite {
+		f.applyGeoSite(key, values, emit)
+	} else {
+		f.applyGeoIP(key, values, emit)
+	}
+}
+
+func (f *entryFilter) applyGeoSite(tag string, rules []string, emit func(string, []string)) {
+	if len(f.tags) == 0 {
+		emit(tag, f.finish(tag, rules))
+		return
+	}
+	lower := strings.ToLower(tag)
+	for _, t := range f.tags {
+		name, selectors := parseTagSelector(t)
+		if name != lower {
+			continue
+		}
+		f.found[name] = true
+		if len(selectors) == 0 {
+			emit(tag, f.finish(tag, rules))
+			continue
+		}
+		selected := geosite.FilterByAttributes(rules, selectors)
+		if len(selected) == 0 {
+			f.warn("no rules in tag '%s' match '%s'", tag, t)
+		}
+		key := tag + "@" + strings.Join(selectors, "@")
+		emit(key, f.finish(key, selected))
+	}
+}
+
+func (f *entryFilter) applyGeoIP(key string, cidrs []string, emit func(string, []string)) {
+	if len(f.countries) == 0 {
+		if values, ok := f.finishGeoIP(key, cidrs); ok {
+			emit(key, values)
+		}
+		return
+	}
+	code := strings.TrimPrefix(key, geoip.ReverseMatchPrefix)
+	for _, c := range f.countries {
+		if c == code {
+			f.found[c] = true
+			if values, ok := f.finishGeoIP(key, cidrs); ok {
+				emit(key, values)
+			}
+			return
+		}
+	}
+}
+
+// finish applies per-entry rule transformations such as pruning and sorting.
+func (f *entryFilter) finish(tag string, values []string) []string {
+	if f.prune {
+		kept, dropped, err := geosite.Prune(values)
+		if err != nil {
+			f.fail(fmt.Errorf("prune %s: %w", tag, err))
+			return values
+		}
+		for _, d := range dropped {
+			f.pruned = append(f.pruned, fmt.Sprintf("%s: %s (covered by %s)", tag, d.Rule, d.By))
+		}
+		values = kept
+	}
+	if f.sortValues {
+		sort.Strings(values)
+	}
+	return values
+}
+
+// finishGeoIP applies per-entry CIDR transformations such as family filtering,
+// canonicalization and aggregation. It reports false when the family filter left nothing to emit.
+func (f *entryFilter) finishGeoIP(key string, cidrs []string) ([]string, bool) {
+	if f.family != geoip.FamilyBoth {
+		selected, err := geoip.FilterFamily(cidrs, f.family)
+		if err != nil {
+			f.fail(fmt.Errorf("filter %s: %w", key, err))
+			return nil, false
+		}
+		f.familySeen += len(cidrs)
+		f.familyKept += len(selected)
+		if len(selected) == 0 && len(cidrs) > 0 {
+			return nil, false
+		}
+		cidrs = selected
+	}
+	if f.canonical {
+		canonicalized, changed, err := geoip.Canonicalize(cidrs)
+		if err != nil {
+			f.fail(fmt.Errorf("canonicalize %s: %w", key, err))
+			return nil, false
+		}
+		f.nonCanonical += changed
+		cidrs = canonicalized
+	} else {
+		f.nonCanonical += geoip.CountHostBits(cidrs)
+	}
+	if f.aggregate {
+		aggregated, err := geoip.Aggregate(cidrs)
+		if err != nil {
+			f.fail(fmt.Errorf("aggregate %s: %w", key, err))
+			return nil, false
+		}
+		f.cidrsIn += len(cidrs)
+		f.cidrsOut += len(aggregated)
+		cidrs = aggregated
+	}
+	return cidrs, true
+}
+
+// fail records the first transformation error; done returns it.
+func (f *entryFilter) fail(err error) {
+	if f.err == nil {
+		f.err = err
+	}
+}
+
+// summary returns informational lines about transformations applied to the entries.
+// Counters that indicate problems in the input are reported by done as warnings.
+func (f *entryFilter) summary() []string {
+	var lines []string
+	if f.family != geoip.FamilyBoth {
+		lines = append(lines, fmt.Sprintf("kept %d of %d CIDRs (%s only)", f.familyKept, f.familySeen, f.family))
+	}
+	if f.prune {
+		lines = append(lines, fmt.Sprintf("pruned %d redundant rules", len(f.pruned)))
+		for i, p := range f.pruned {
+			if i == maxListed {
+				lines = append(lines, fmt.Sprintf("  ... and %d more", len(f.pruned)-i))
+				break
+			}
+			lines = append(lines, "  "+p)
+		}
+	}
+	if f.aggregate {
+		lines = append(lines, fmt.Sprintf("aggregated %d CIDRs into %d (%d collapsed)", f.cidrsIn, f.cidrsOut, f.cidrsIn-f.cidrsOut))
+	}
+	return lines
+}
+
+// done records warnings for requested tags or countries that matched no entry
+// and returns an error if a transformation failed or a filter matched nothing.
+func (f *entryFilter) done() error {
+	if f.err != nil {
+		return f.err
+	}
+	if f.nonCanonical > 0 && f.canonical {
+		f.warn("%d CIDRs had host bits set and were canonicalized", f.nonCanonical)
+	} else if f.nonCanonical > 0 {
+		f.warn("%d CIDRs have host bits set (use --canonical)", f.nonCanonical)
+	}
+	if f.isGeoSite {
+		for _, t := range f.tags {
+			name, _ := parseTagSelector(t)
+			if !f.found[name] {
+				f.warn("tag '%s' not found", name)
+			}
+		}
+	} else {
+		for _, c := range f.countries {
+			if !f.found[c] {
+				f.warn("country code '%s' not found", c)
+			}
+		}
+	}
+	if f.active() && len(f.found) == 0 {
+		if f.isGeoSite {
+			return fmt.Errorf("no valid tags found")
+		}
+		return fmt.Errorf("no valid country codes found")
+	}
+	return nil
+}
+
+// filterAll applies the filter to every entry of a fully decoded result.
+func (f *entryFilter) filterAll(data map[string][]string) (map[string][]string, error) {
+	filtered := make(map[string][]string, len(data))
+	for key, values := range data {
+		f.apply(key, values, func(k string, v []string) {
+			filtered[k] = v
+		})
+	}
+	return filtered, f.done()
+}
+
+func uniqueStrings(items []string) []string {
+	seen := make(map[string]bool, len(items))
+	result := items[:0]
+	for _, item := range items {
+		if !seen[item] {
+			seen[item] = true
+			result = append(result, item)
+		}
+	}
+	return result
+}
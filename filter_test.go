@@ -0,0 +1,131 @@
+// filter_test.go
+package main
+
+import "testing"
+
+func TestEntryFilterGeoSite(t *testing.T) {
+	resetFlags()
+	*tagFilter = "google@cn,missing,google"
+	*sortKeys = true
+
+	f := newEntryFilter(true)
+	out := map[string][]string{}
+	emit := func(k string, v []string) { out[k] = v }
+	f.apply("GOOGLE", []string{"full:www.google.com", "domain:google.cn @cn"}, emit)
+	f.apply("OTHER", []string{"domain:example.com"}, emit)
+
+	if err := f.done(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(out) != 2 || len(out["GOOGLE@cn"]) != 1 || out["GOOGLE"][0] != "domain:google.cn @cn" {
+		t.Errorf("unexpected output: %v", out)
+	}
+	if len(f.warnings) != 1 {
+		t.Errorf("expected 1 warning, got %v", f.warnings)
+	}
+}
+
+func TestEntryFilterGeoIPNoMatch(t *testing.T) {
+	resetFlags()
+	*countryFilter = "xx"
+
+	f := newEntryFilter(false)
+	f.apply("US", []string{"1.0.0.0/24"}, func(string, []string) {
+		t.Error("unexpected emit")
+	})
+	if err := f.done(); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestEntryFilterAggregate(t *testing.T) {
+	resetFlags()
+	*aggregate = true
+
+	f := newEntryFilter(false)
+	var got []string
+	f.apply("US", []string{"10.0.1.0/24", "10.0.0.0/24", "10.0.0.5/32"}, func(_ string, v []string) { got = v })
+	if err := f.done(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 1 || got[0] != "10.0.0.0/23" {
+		t.Errorf("unexpected CIDRs: %v", got)
+	}
+	if summary := f.summary(); len(summary) != 1 || summary[0] != "aggregated 3 CIDRs into 1 (2 collapsed)" {
+		t.Errorf("unexpected summary: %v", summary)
+	}
+}
+
+func TestEntryFilterFamily(t *testing.T) {
+	resetFlags()
+	*familyFlag = "ipv6"
+
+	f := newEntryFilter(false)
+	got := make(map[string][]string)
+	emit := func(k string, v []string) { got[k] = v }
+	f.apply("US", []string{"8.8.8.0/24", "2001:4860::/32"}, emit)
+	f.apply("RU", []string{"5.3.0.0/16"}, emit)
+	if err := f.done(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 1 || len(got["US"]) != 1 || got["US"][0] != "2001:4860::/32" {
+		t.Errorf("unexpected entries: %v", got)
+	}
+	if summary := f.summary(); len(summary) != 1 || summary[0] != "kept 1 of 3 CIDRs (ipv6 only)" {
+		t.Errorf("unexpected summary: %v", summary)
+	}
+}
+
+func TestEntryFilterPrune(t *testing.T) {
+	resetFlags()
+	*pruneFlag = true
+
+	f := newEntryFilter(true)
+	var got []string
+	f.apply("google", []string{"full:mail.google.com", "domain:google.com", "domain:google.com"}, func(_ string, v []string) { got = v })
+	if err := f.done(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 1 || got[0] != "domain:google.com" {
+		t.Errorf("unexpected rules: %v", got)
+	}
+	summary := f.summary()
+	if len(summary) != 3 || summary[0] != "pruned 2 redundant rules" || summary[1] != "  google: full:mail.google.com (covered by domain:google.com)" {
+		t.Errorf("unexpected summary: %q", summary)
+	}
+}
+
+func TestEntryFilterHostBits(t *testing.T) {
+	resetFlags()
+
+	f := newEntryFilter(false)
+	var got []string
+	f.apply("US", []string{"1.2.3.4/24", "10.0.0.0/8"}, func(_ string, v []string) { got = v })
+	if err := f.done(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 2 || got[0] != "1.2.3.4/24" {
+		t.Errorf("unexpected CIDRs: %v", got)
+	}
+	if len(f.warnings) != 1 || f.warnings[0] != "1 CIDRs have host bits set (use --canonical)" {
+		t.Errorf("unexpected warnings: %v", f.warnings)
+	}
+}
+
+func TestEntryFilterCanonical(t *testing.T) {
+	resetFlags()
+	*canonical = true
+
+	f := newEntryFilter(false)
+	var got []string
+	f.apply("US", []string{"1.2.3.4/24", "10.0.0.0/8"}, func(_ string, v []string) { got = v })
+	if err := f.done(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 2 || got[0] != "1.2.3.0/24" || got[1] != "10.0.0.0/8" {
+		t.Errorf("unexpected CIDRs: %v", got)
+	}
+	if len(f.warnings) != 1 || f.warnings[0] != "1 CIDRs had host bits set and were canonicalized" {
+		t.Errorf("unexpected warnings: %v", f.warnings)
+	}
+}
@@ -1,8 +1,10 @@
-module dat2json
+module github.com/Viktor45/dat2json
 
 go 1.23
 
 require (
+	github.com/klauspost/compress v1.18.0
+	go4.org/netipx v0.0.0-20231129151722-fdeea329fbba
 	google.golang.org/protobuf v1.36.11
 	gopkg.in/yaml.v3 v3.0.1
 )
@@ -0,0 +1,280 @@
+// Package expr parses and evaluates set expressions over tags, such as "(google | youtube) & !cn".
+package expr
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Op is a binary set operator.
+type Op byte
+
+// Binary set operators. Intersection binds tighter than union and subtraction,
+// which are left-associative at the same level.
+const (
+	Union     Op = '|'
+	Intersect Op = '&'
+	Subtract  Op = '-'
+)
+
+// ErrSyntax is returned for malformed expressions.
+var ErrSyntax = errors.New("expression syntax error")
+
+// Node is a parsed set expression.
+type Node interface {
+	String() string
+}
+
+// Tag references a named entry such as a country code or a geosite tag.
+type Tag struct {
+	Name string
+}
+
+// Not is the complement of its operand.
+type Not struct {
+	X Node
+}
+
+// Binary combines two operands with a set operator.
+type Binary struct {
+	Op   Op
+	X, Y Node
+}
+
+func (t *Tag) String() string { return t.Name }
+func (n *Not) String() string { return "!" + n.X.String() }
+func (b *Binary) String() string {
+	return "(" + b.X.String() + " " + string(b.Op) + " " + b.Y.String() + ")"
+}
+
+// Tags returns the distinct tag names referenced by the expression in order of appearance.
+func Tags(n Node) []string {
+	var names []string
+	seen := make(map[string]bool)
+	var walk func(Node)
+	walk = func(n Node) {
+		switch n := n.(type) {
+		case *Tag:
+			if !seen[n.Name] {
+				seen[n.Name] = true
+				names = append(names, n.Name)
+			}
+		case *Not:
+			walk(n.X)
+		case *Binary:
+			walk(n.X)
+			walk(n.Y)
+		}
+	}
+	walk(n)
+	return names
+}
+
+// Algebra evaluates set operations over values of type T.
+type Algebra[T any] interface {
+	Tag(name string) (T, error)
+	Union(x, y T) (T, error)
+	Intersect(x, y T) (T, error)
+	Subtract(x, y T) (T, error)
+	Complement(x T) (T, error)
+}
+
+// Eval computes the value of an expression with the given algebra.
+func Eval[T any](n Node, a Algebra[T]) (T, error) {
+	var zero T
+	switch n := n.(type) {
+	case *Tag:
+		return a.Tag(n.Name)
+	case *Not:
+		x, err := Eval(n.X, a)
+		if err != nil {
+			return zero, err
+		}
+		return a.Complement(x)
+	case *Binary:
+		x, err := Eval(n.X, a)
+		if err != nil {
+			return zero, err
+		}
+		y, err := Eval(n.Y, a)
+		if err != nil {
+			return zero, err
+		}
+		switch n.Op {
+		case Union:
+			return a.Union(x, y)
+		case Intersect:
+			return a.Intersect(x, y)
+		case Subtract:
+			return a.Subtract(x, y)
+		}
+		return zero, fmt.Errorf("unknown operator %q", n.Op)
+	}
+	return zero, fmt.Errorf("unknown expression node %T", n)
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokTag
+	tokOp
+	tokNot
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// tokenize splits an expression into tokens. Tag names may contain '-' and '!'
+// (e.g. "geolocation-!cn"), so '-' is an operator only as a standalone word and
+// '!' only at the start of an operand.
+func tokenize(s string) []token {
+	var tokens []token
+	i := 0
+	for i < len(s) {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{tokLParen, "(", i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{tokRParen, ")", i})
+			i++
+		case c == '|' || c == '&':
+			tokens = append(tokens, token{tokOp, string(c), i})
+			i++
+		case c == '!':
+			tokens = append(tokens, token{tokNot, "!", i})
+			i++
+		default:
+			start := i
+			for i < len(s) && !strings.ContainsRune(" \t\n()|&", rune(s[i])) {
+				i++
+			}
+			word := s[start:i]
+			if word == "-" {
+				tokens = append(tokens, token{tokOp, word, start})
+			} else {
+				tokens = append(tokens, token{tokTag, word, start})
+			}
+		}
+	}
+	return append(tokens, token{tokEOF, "", len(s)})
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token { return p.tokens[p.pos] }
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) errorf(t token, format string, args ...any) error {
+	return fmt.Errorf("%w at offset %d: %s", ErrSyntax, t.pos, fmt.Sprintf(format, args...))
+}
+
+// Parse parses a set expression such as "CN - PRIVATE" or "!(RU | BY)".
+//
+// Grammar:
+//
+//	expr    = term { ("|" | "-") term }
+//	term    = unary { "&" unary }
+//	unary   = "!" unary | primary
+//	primary = TAG | "(" expr ")"
+//
+// Subtraction needs whitespace around '-' because tag names may contain it.
+func Parse(s string) (Node, error) {
+	p := &parser{tokens: tokenize(s)}
+	n, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, p.errorf(t, "unexpected %q", t.text)
+	}
+	return n, nil
+}
+
+func (p *parser) parseExpr() (Node, error) {
+	x, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if t.kind != tokOp || (t.text != "|" && t.text != "-") {
+			return x, nil
+		}
+		p.next()
+		y, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		x = &Binary{Op: Op(t.text[0]), X: x, Y: y}
+	}
+}
+
+func (p *parser) parseTerm() (Node, error) {
+	x, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokOp && p.peek().text == "&" {
+		p.next()
+		y, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		x = &Binary{Op: Intersect, X: x, Y: y}
+	}
+	return x, nil
+}
+
+func (p *parser) parseUnary() (Node, error) {
+	if p.peek().kind == tokNot {
+		p.next()
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &Not{X: x}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (Node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokTag:
+		return &Tag{Name: t.text}, nil
+	case tokLParen:
+		x, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if r := p.next(); r.kind != tokRParen {
+			return nil, p.errorf(r, "expected ')'")
+		}
+		return x, nil
+	case tokEOF:
+		return nil, p.errorf(t, "unexpected end of expression")
+	default:
+		return nil, p.errorf(t, "unexpected %q", t.text)
+	}
+}
@@ -0,0 +1,127 @@
+// internal/expr/expr_test.go
+package expr
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"CN", "CN"},
+		{"CN - PRIVATE", "(CN - PRIVATE)"},
+		{"!(RU|BY)", "!(RU | BY)"},
+		{"A | B & C", "(A | (B & C))"},
+		{"A - B | C", "((A - B) | C)"},
+		{"geolocation-!cn - google", "(geolocation-!cn - google)"},
+		{"!!A&!B", "(!!A & !B)"},
+	}
+	for _, tt := range tests {
+		n, err := Parse(tt.in)
+		if err != nil {
+			t.Fatalf("Parse(%q): unexpected error: %v", tt.in, err)
+		}
+		if got := n.String(); got != tt.want {
+			t.Errorf("Parse(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, in := range []string{"", "A |", "(A", "A B", "A)", "| A", "!"} {
+		if _, err := Parse(in); !errors.Is(err, ErrSyntax) {
+			t.Errorf("Parse(%q): expected syntax error, got %v", in, err)
+		}
+	}
+}
+
+func TestTags(t *testing.T) {
+	n, err := Parse("(A | B) - !A & C")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := Tags(n); !reflect.DeepEqual(got, []string{"A", "B", "C"}) {
+		t.Errorf("unexpected tags: %v", got)
+	}
+}
+
+// letters is a toy algebra over sets of letters, with "abcdef" as the universe.
+type letters map[string]string
+
+func (l letters) Tag(name string) (string, error) {
+	if v, ok := l[name]; ok {
+		return v, nil
+	}
+	return "", errors.New("unknown tag " + name)
+}
+
+func (letters) Union(x, y string) (string, error) { return normalize(x + y), nil }
+
+func (letters) Intersect(x, y string) (string, error) {
+	var b strings.Builder
+	for _, c := range x {
+		if strings.ContainsRune(y, c) {
+			b.WriteRune(c)
+		}
+	}
+	return normalize(b.String()), nil
+}
+
+func (letters) Subtract(x, y string) (string, error) {
+	var b strings.Builder
+	for _, c := range x {
+		if !strings.ContainsRune(y, c) {
+			b.WriteRune(c)
+		}
+	}
+	return normalize(b.String()), nil
+}
+
+func (l letters) Complement(x string) (string, error) { return l.Subtract("abcdef", x) }
+
+func normalize(s string) string {
+	chars := strings.Split(s, "")
+	sort.Strings(chars)
+	var out []string
+	for i, c := range chars {
+		if i == 0 || c != chars[i-1] {
+			out = append(out, c)
+		}
+	}
+	return strings.Join(out, "")
+}
+
+func TestEval(t *testing.T) {
+	sets := letters{"A": "abc", "B": "cd", "C": "e"}
+	tests := []struct {
+		in, want string
+	}{
+		{"A | B", "abcd"},
+		{"A & B", "c"},
+		{"A - B", "ab"},
+		{"!(A | C)", "df"},
+		{"A | B - A", "d"},
+	}
+	for _, tt := range tests {
+		n, err := Parse(tt.in)
+		if err != nil {
+			t.Fatalf("Parse(%q): unexpected error: %v", tt.in, err)
+		}
+		got, err := Eval[string](n, sets)
+		if err != nil {
+			t.Fatalf("Eval(%q): unexpected error: %v", tt.in, err)
+		}
+		if got != tt.want {
+			t.Errorf("Eval(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+	n, _ := Parse("A | X")
+	if _, err := Eval[string](n, sets); err == nil {
+		t.Error("expected error for unknown tag")
+	}
+}
@@ -0,0 +1,36 @@
+// internal/geodata/errors.go
+package geodata
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// DecodeError describes where decoding a .dat file failed. Use errors.As to
+// retrieve it from errors returned by the geoip and geosite decoders.
+type DecodeError struct {
+	// Offset is the byte position in the file at which decoding failed.
+	Offset int64
+	// Tag is the tag or country code of the entry being decoded, if it was read.
+	Tag string
+	// Index is the position of the failing CIDR or domain record within the entry,
+	// or -1 if the failure is not within a record.
+	Index int
+	// Err is the underlying cause.
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	msg := "offset " + strconv.FormatInt(e.Offset, 10)
+	if e.Tag != "" {
+		msg += fmt.Sprintf(": tag %q", e.Tag)
+	}
+	if e.Index >= 0 {
+		msg += fmt.Sprintf(": record %d", e.Index)
+	}
+	return msg + ": " + e.Err.Error()
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,27 @@
+// internal/geodata/errors_test.go
+package geodata
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+)
+
+func TestDecodeError(t *testing.T) {
+	err := fmt.Errorf("wrapped: %w", &DecodeError{Offset: 42, Tag: "CN", Index: 3, Err: io.ErrUnexpectedEOF})
+	var de *DecodeError
+	if !errors.As(err, &de) {
+		t.Fatal("expected errors.As to find DecodeError")
+	}
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Error("expected errors.Is to reach the cause")
+	}
+	if got := de.Error(); got != `offset 42: tag "CN": record 3: unexpected EOF` {
+		t.Errorf("unexpected message: %s", got)
+	}
+	de = &DecodeError{Offset: 7, Index: -1, Err: io.EOF}
+	if got := de.Error(); got != "offset 7: EOF" {
+		t.Errorf("unexpected message: %s", got)
+	}
+}
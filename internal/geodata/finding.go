@@ -0,0 +1,64 @@
+// internal/geodata/finding.go
+package geodata
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Severity classifies a validation finding.
+type Severity string
+
+const (
+	// SeverityError marks data that routers reject or match incorrectly.
+	SeverityError Severity = "error"
+	// SeverityWarning marks data that works but is likely a mistake.
+	SeverityWarning Severity = "warning"
+)
+
+// Finding is a single problem reported by validation.
+type Finding struct {
+	Severity Severity `json:"severity" yaml:"severity"`
+	Tag      string   `json:"tag" yaml:"tag"`
+	// Index is the position of the offending value within its tag, or -1 for
+	// findings about the tag itself.
+	Index   int    `json:"index" yaml:"index"`
+	Value   string `json:"value,omitempty" yaml:"value,omitempty"`
+	Message string `json:"message" yaml:"message"`
+}
+
+func (f Finding) String() string {
+	if f.Index < 0 {
+		return fmt.Sprintf("%s: %s: %s", f.Severity, f.Tag, f.Message)
+	}
+	return fmt.Sprintf("%s: %s[%d] %s: %s", f.Severity, f.Tag, f.Index, f.Value, f.Message)
+}
+
+// SortFindings orders findings by tag and index, keeping the check order for ties.
+func SortFindings(findings []Finding) {
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Tag != findings[j].Tag {
+			return findings[i].Tag < findings[j].Tag
+		}
+		return findings[i].Index < findings[j].Index
+	})
+}
+
+// ValidTag reports whether a tag or country code consists only of ASCII letters,
+// digits and the separators '-', '_', '.' and '!' used by upstream lists
+// (e.g. "geolocation-!cn").
+func ValidTag(tag string) bool {
+	if tag == "" {
+		return false
+	}
+	for i := 0; i < len(tag); i++ {
+		c := tag[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == '!':
+		default:
+			return false
+		}
+	}
+	return true
+}
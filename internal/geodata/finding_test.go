@@ -0,0 +1,45 @@
+// internal/geodata/finding_test.go
+package geodata
+
+import "testing"
+
+func TestValidTag(t *testing.T) {
+	for _, tag := range []string{"CN", "geolocation-!cn", "category-ads-all", "tld_x.y"} {
+		if !ValidTag(tag) {
+			t.Errorf("ValidTag(%q) = false, want true", tag)
+		}
+	}
+	for _, tag := range []string{"", "google@cn", "a b", "тест", "a/b"} {
+		if ValidTag(tag) {
+			t.Errorf("ValidTag(%q) = true, want false", tag)
+		}
+	}
+}
+
+func TestSortFindings(t *testing.T) {
+	findings := []Finding{
+		{Tag: "b", Index: 0, Message: "1"},
+		{Tag: "a", Index: 2, Message: "2"},
+		{Tag: "a", Index: -1, Message: "3"},
+		{Tag: "a", Index: 2, Message: "4"},
+	}
+	SortFindings(findings)
+	var got string
+	for _, f := range findings {
+		got += f.Message
+	}
+	if got != "3241" {
+		t.Errorf("unexpected order: %s", got)
+	}
+}
+
+func TestFindingString(t *testing.T) {
+	f := Finding{Severity: SeverityError, Tag: "CN", Index: 3, Value: "1.2.3.4/40", Message: "prefix length 40 exceeds 32"}
+	if got := f.String(); got != "error: CN[3] 1.2.3.4/40: prefix length 40 exceeds 32" {
+		t.Errorf("unexpected string: %s", got)
+	}
+	f = Finding{Severity: SeverityWarning, Tag: "EMPTY", Index: -1, Message: "tag has no entries"}
+	if got := f.String(); got != "warning: EMPTY: tag has no entries" {
+		t.Errorf("unexpected string: %s", got)
+	}
+}
@@ -0,0 +1,94 @@
+// internal/geodata/index.go
+package geodata
+
+import "google.golang.org/protobuf/encoding/protowire"
+
+// IndexEntry locates a single tag or country entry inside a .dat file.
+type IndexEntry struct {
+	// Tag is the entry key as reported by Decode, including any marker prefix.
+	Tag string
+	// Offset and Length delimit the encoded entry within the file.
+	Offset int
+	Length int
+}
+
+// IndexProtobufList scans the "repeated entry = 1" field of a GeoIPList or GeoSiteList
+// without unmarshaling entries. The key function extracts the tag from each raw entry.
+// Errors are returned as *DecodeError at the same location a streaming Reader reports:
+// the start of a field that cannot be read, or the failing part of an entry as found by
+// LocateProtobufError with check validating a single record.
+func IndexProtobufList(data []byte, key func(entry []byte) (string, error), check func(record []byte) error) ([]IndexEntry, error) {
+	var index []IndexEntry
+	for pos := 0; pos < len(data); {
+		start := pos
+		num, typ, n := protowire.ConsumeTag(data[pos:])
+		if n < 0 {
+			return nil, &DecodeError{Offset: int64(start), Index: -1, Err: protowire.ParseError(n)}
+		}
+		pos += n
+		if num != 1 || typ != protowire.BytesType {
+			m := protowire.ConsumeFieldValue(num, typ, data[pos:])
+			if m < 0 {
+				return nil, &DecodeError{Offset: int64(start), Index: -1, Err: protowire.ParseError(m)}
+			}
+			pos += m
+			continue
+		}
+		entry, m := protowire.ConsumeBytes(data[pos:])
+		if m < 0 {
+			// Like ReadListEntry, name the entry from the bytes that are left of it.
+			var partial []byte
+			if _, k := protowire.ConsumeVarint(data[pos:]); k > 0 {
+				partial = data[pos+k:]
+			}
+			tag, record, _ := LocateProtobufError(partial, check)
+			return nil, &DecodeError{Offset: int64(start), Tag: tag, Index: record, Err: protowire.ParseError(m)}
+		}
+		offset := pos + m - len(entry)
+		tag, err := key(entry)
+		if err != nil {
+			tag, record, at := LocateProtobufError(entry, check)
+			return nil, &DecodeError{Offset: int64(offset + at), Tag: tag, Index: record, Err: err}
+		}
+		index = append(index, IndexEntry{Tag: tag, Offset: offset, Length: len(entry)})
+		pos += m
+	}
+	return index, nil
+}
+
+// ProtobufFields returns the last string value of field 1 and the last varint value of
+// field 3 of a raw message, which is all an index needs from GeoIP and GeoSite entries.
+func ProtobufFields(msg []byte) (string, uint64, error) {
+	var str string
+	var varint uint64
+	for len(msg) > 0 {
+		num, typ, n := protowire.ConsumeTag(msg)
+		if n < 0 {
+			return "", 0, protowire.ParseError(n)
+		}
+		msg = msg[n:]
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			v, m := protowire.ConsumeBytes(msg)
+			if m < 0 {
+				return "", 0, protowire.ParseError(m)
+			}
+			str = string(v)
+			n = m
+		case num == 3 && typ == protowire.VarintType:
+			v, m := protowire.ConsumeVarint(msg)
+			if m < 0 {
+				return "", 0, protowire.ParseError(m)
+			}
+			varint = v
+			n = m
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, msg)
+			if n < 0 {
+				return "", 0, protowire.ParseError(n)
+			}
+		}
+		msg = msg[n:]
+	}
+	return str, varint, nil
+}
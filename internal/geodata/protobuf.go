@@ -0,0 +1,128 @@
+// internal/geodata/protobuf.go
+package geodata
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// ErrInvalidWireType is returned when a Protobuf stream contains an unsupported wire type.
+var ErrInvalidWireType = errors.New("invalid wire type")
+
+// ReadListEntry reads the next "repeated entry = 1" message of a GeoIPList or GeoSiteList
+// stream and returns its raw bytes. Unknown fields are skipped. It returns io.EOF when the
+// stream ends on an entry boundary, and the bytes read so far when it ends inside an entry.
+func ReadListEntry(r ByteReader) ([]byte, error) {
+	for {
+		tag, err := binary.ReadUvarint(r)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, io.EOF
+			}
+			return nil, err
+		}
+		num, typ := protowire.DecodeTag(tag)
+		if num == 1 && typ == protowire.BytesType {
+			length, err := binary.ReadUvarint(r)
+			if err != nil {
+				return nil, unexpectedEOF(err)
+			}
+			return ReadBytes(r, length)
+		}
+		if err := skipField(r, typ); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// skipField discards the value of a field with the given wire type.
+func skipField(r ByteReader, typ protowire.Type) error {
+	var n uint64
+	switch typ {
+	case protowire.VarintType:
+		_, err := binary.ReadUvarint(r)
+		return unexpectedEOF(err)
+	case protowire.Fixed32Type:
+		n = 4
+	case protowire.Fixed64Type:
+		n = 8
+	case protowire.BytesType:
+		length, err := binary.ReadUvarint(r)
+		if err != nil {
+			return unexpectedEOF(err)
+		}
+		n = length
+	default:
+		return fmt.Errorf("%w %d", ErrInvalidWireType, typ)
+	}
+	if int64(n) < 0 {
+		return io.ErrUnexpectedEOF
+	}
+	_, err := io.CopyN(io.Discard, r, int64(n))
+	return unexpectedEOF(err)
+}
+
+// unexpectedEOF converts io.EOF inside a field into io.ErrUnexpectedEOF.
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// CompleteFields returns the longest prefix of a truncated raw message that holds only
+// complete fields, so that the fields read before the truncation can still be decoded.
+func CompleteFields(raw []byte) []byte {
+	pos := 0
+	for pos < len(raw) {
+		num, typ, n := protowire.ConsumeTag(raw[pos:])
+		if n < 0 {
+			break
+		}
+		m := protowire.ConsumeFieldValue(num, typ, raw[pos+n:])
+		if m < 0 {
+			break
+		}
+		pos += n + m
+	}
+	return raw[:pos]
+}
+
+// LocateProtobufError walks the fields of a raw GeoIP or GeoSite message that failed
+// to unmarshal and finds the first malformed part. It returns the tag (field 1) if it
+// could be read, the index of the failing repeated record (field 2) or -1, and the
+// offset of the failing field within raw. check validates a single record.
+func LocateProtobufError(raw []byte, check func(record []byte) error) (tag string, index int, offset int) {
+	records := 0
+	for pos := 0; pos < len(raw); {
+		num, typ, n := protowire.ConsumeTag(raw[pos:])
+		if n < 0 {
+			return tag, -1, pos
+		}
+		m := protowire.ConsumeFieldValue(num, typ, raw[pos+n:])
+		if m < 0 {
+			if num == 2 {
+				return tag, records, pos
+			}
+			return tag, -1, pos
+		}
+		if typ == protowire.BytesType {
+			value, _ := protowire.ConsumeBytes(raw[pos+n:])
+			switch num {
+			case 1:
+				tag = string(value)
+			case 2:
+				if err := check(value); err != nil {
+					return tag, records, pos
+				}
+				records++
+			}
+		}
+		pos += n + m
+	}
+	return tag, -1, 0
+}
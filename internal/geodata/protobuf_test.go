@@ -0,0 +1,61 @@
+// internal/geodata/protobuf_test.go
+package geodata
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestReadListEntry(t *testing.T) {
+	// field 2 (varint, skipped), field 1 "ab", field 1 "c"
+	data := []byte{0x10, 0x05, 0x0a, 0x02, 'a', 'b', 0x0a, 0x01, 'c'}
+	r := bytes.NewReader(data)
+	for _, expected := range []string{"ab", "c"} {
+		entry, err := ReadListEntry(r)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if string(entry) != expected {
+			t.Errorf("expected %q, got %q", expected, entry)
+		}
+	}
+	if _, err := ReadListEntry(r); err != io.EOF {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+}
+
+func TestReadListEntryTruncated(t *testing.T) {
+	r := bytes.NewReader([]byte{0x0a, 0x05, 'a'})
+	if _, err := ReadListEntry(r); err != io.ErrUnexpectedEOF {
+		t.Errorf("expected io.ErrUnexpectedEOF, got %v", err)
+	}
+}
+
+func TestLocateProtobufError(t *testing.T) {
+	raw := protowire.AppendTag(nil, 1, protowire.BytesType)
+	raw = protowire.AppendString(raw, "US")
+	raw = protowire.AppendTag(raw, 2, protowire.BytesType)
+	raw = protowire.AppendBytes(raw, []byte("ok"))
+	bad := len(raw)
+	raw = protowire.AppendTag(raw, 2, protowire.BytesType)
+	raw = protowire.AppendBytes(raw, []byte("bad"))
+
+	check := func(record []byte) error {
+		if string(record) == "bad" {
+			return io.ErrUnexpectedEOF
+		}
+		return nil
+	}
+	tag, index, offset := LocateProtobufError(raw, check)
+	if tag != "US" || index != 1 || offset != bad {
+		t.Errorf("unexpected location: %q %d %d", tag, index, offset)
+	}
+
+	tag, index, offset = LocateProtobufError(raw[:len(raw)-1], check)
+	if tag != "US" || index != 1 || offset != bad {
+		t.Errorf("unexpected location for truncated record: %q %d %d", tag, index, offset)
+	}
+}
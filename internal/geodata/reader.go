@@ -0,0 +1,50 @@
+// internal/geodata/reader.go
+package geodata
+
+import (
+	"bufio"
+	"io"
+)
+
+// CountingReader is a buffered reader that tracks how many bytes have been consumed,
+// so decoders can report the file offset of an error.
+type CountingReader struct {
+	r      *bufio.Reader
+	offset int64
+}
+
+// NewCountingReader returns a CountingReader over r whose offsets start at base.
+func NewCountingReader(r io.Reader, base int64) *CountingReader {
+	return &CountingReader{r: bufio.NewReader(r), offset: base}
+}
+
+// Offset returns the position of the next unread byte.
+func (c *CountingReader) Offset() int64 {
+	return c.offset
+}
+
+func (c *CountingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.offset += int64(n)
+	return n, err
+}
+
+func (c *CountingReader) ReadByte() (byte, error) {
+	b, err := c.r.ReadByte()
+	if err == nil {
+		c.offset++
+	}
+	return b, err
+}
+
+// Peek returns the next n bytes without consuming them.
+func (c *CountingReader) Peek(n int) ([]byte, error) {
+	return c.r.Peek(n)
+}
+
+// Discard skips the next n bytes.
+func (c *CountingReader) Discard(n int) (int, error) {
+	d, err := c.r.Discard(n)
+	c.offset += int64(d)
+	return d, err
+}
@@ -0,0 +1,32 @@
+// internal/geodata/reader_test.go
+package geodata
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestCountingReader(t *testing.T) {
+	r := NewCountingReader(bytes.NewReader([]byte("\x03abcdef")), 10)
+	if _, err := r.Peek(2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r.Offset() != 10 {
+		t.Errorf("Peek must not advance, offset %d", r.Offset())
+	}
+	s, err := ReadVarintString(r)
+	if err != nil || s != "abc" {
+		t.Fatalf("unexpected result %q, %v", s, err)
+	}
+	if _, err := r.Discard(1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r.Offset() != 15 {
+		t.Errorf("expected offset 15, got %d", r.Offset())
+	}
+	rest, _ := io.ReadAll(r)
+	if string(rest) != "ef" || r.Offset() != 17 {
+		t.Errorf("unexpected tail %q at offset %d", rest, r.Offset())
+	}
+}
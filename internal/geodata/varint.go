@@ -3,24 +3,50 @@
 package geodata
 
 import (
-	"bytes"
 	"encoding/binary"
 	"fmt"
+	"io"
 )
 
+// MaxStringLength limits varint-prefixed strings read from streams of unknown size.
+const MaxStringLength = 1 << 20
+
+// ByteReader is a reader that also supports reading single bytes, such as *bytes.Reader or *bufio.Reader.
+type ByteReader interface {
+	io.Reader
+	io.ByteReader
+}
+
 // ReadVarintString reads a varint-prefixed string from the reader.
 // Returns error if length is invalid or exceeds remaining bytes.
-func ReadVarintString(r *bytes.Reader) (string, error) {
+func ReadVarintString(r ByteReader) (string, error) {
 	length, err := binary.ReadUvarint(r)
 	if err != nil {
 		return "", err
 	}
-	if length > uint64(r.Len()) {
+	limit := uint64(MaxStringLength)
+	if lr, ok := r.(interface{ Len() int }); ok {
+		limit = uint64(lr.Len())
+	}
+	if length > limit {
 		return "", fmt.Errorf("string too long")
 	}
 	buf := make([]byte, length)
-	if _, err := r.Read(buf); err != nil {
+	if _, err := io.ReadFull(r, buf); err != nil {
 		return "", err
 	}
 	return string(buf), nil
 }
+
+// ReadBytes reads exactly n bytes without allocating more than is actually available.
+// On a short read it returns the bytes read so far with io.ErrUnexpectedEOF.
+func ReadBytes(r io.Reader, n uint64) ([]byte, error) {
+	buf, err := io.ReadAll(io.LimitReader(r, int64(n)))
+	if err != nil {
+		return buf, err
+	}
+	if uint64(len(buf)) != n {
+		return buf, io.ErrUnexpectedEOF
+	}
+	return buf, nil
+}
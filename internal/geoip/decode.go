@@ -5,10 +5,11 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
+	"io"
 	"net"
 
-	"dat2json/internal/geodata"
-	"dat2json/internal/geodata/router"
+	"github.com/Viktor45/dat2json/internal/geodata"
+	"github.com/Viktor45/dat2json/internal/geodata/router"
 
 	"google.golang.org/protobuf/proto"
 )
@@ -18,90 +19,230 @@ const (
 	magicHeaderSize  = 4
 )
 
+// ReverseMatchPrefix marks country codes of entries with the reverse_match flag set.
+const ReverseMatchPrefix = "!"
+
 // ErrInvalidFormat is returned when the data does not represent a valid geoip.dat file.
 var ErrInvalidFormat = fmt.Errorf("not a valid geoip.dat file")
 
 // Decode decodes binary or Protobuf geoip data into a map of country codes to CIDR lists.
+// Entries with reverse_match set are keyed as ReverseMatchPrefix + country code, e.g. "!CN".
+// Errors in corrupted data are returned as *geodata.DecodeError.
 func Decode(data []byte) (map[string][]string, error) {
-	if len(data) >= magicHeaderSize && string(data[:magicHeaderSize]) == magicHeaderGeoIP {
-		return decodeBinary(data)
+	result, err := DecodePartial(data)
+	if err != nil {
+		return nil, err
 	}
-	return decodeProtobuf(data)
+	return result, nil
 }
 
-func decodeBinary(data []byte) (map[string][]string, error) {
-	if len(data) < 5 {
-		return nil, ErrInvalidFormat
-	}
-	r := bytes.NewReader(data[5:])
+// DecodePartial is like Decode, but on corrupted data it returns everything decoded
+// before the corruption point along with the error, including the CIDRs read so far
+// from the entry that failed.
+func DecodePartial(data []byte) (map[string][]string, error) {
+	r := NewReader(bytes.NewReader(data))
 	result := make(map[string][]string)
-
-	for r.Len() > 0 {
-		countryCode, err := geodata.ReadVarintString(r)
-		if err != nil {
-			return nil, fmt.Errorf("read country code: %w", err)
+	for r.Next() {
+		code, cidrs := r.Entry()
+		result[code] = cidrs
+	}
+	if err := r.Err(); err != nil {
+		if code, cidrs := r.Entry(); code != "" && len(cidrs) > 0 {
+			if _, ok := result[code]; !ok {
+				result[code] = cidrs
+			}
 		}
+		return result, err
+	}
+	return result, nil
+}
 
-		count, err := binary.ReadUvarint(r)
-		if err != nil {
-			return nil, fmt.Errorf("read CIDR count: %w", err)
+// Reader decodes a geoip.dat stream one country at a time, so memory use is bounded
+// by the largest entry rather than the whole file.
+type Reader struct {
+	r       *geodata.CountingReader
+	started bool
+	binary  bool
+	code    string
+	cidrs   []string
+	err     error
+}
+
+// NewReader returns a Reader that decodes binary or Protobuf geoip data from r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: geodata.NewCountingReader(r, 0)}
+}
+
+// Next advances to the next entry and reports whether one is available.
+// After Next returns false, Err reports any decoding error.
+func (r *Reader) Next() bool {
+	if r.err != nil {
+		return false
+	}
+	if !r.started {
+		r.started = true
+		if r.err = r.detect(); r.err != nil {
+			return false
 		}
+	}
+	var err error
+	if r.binary {
+		r.code, r.cidrs, err = r.nextBinary()
+	} else {
+		r.code, r.cidrs, err = r.nextProtobuf()
+	}
+	if err != nil {
+		r.err = err
+		return false
+	}
+	return true
+}
 
-		var cidrs []string
-		for i := uint64(0); i < count; i++ {
-			ip4 := make([]byte, 4)
-			if _, err := r.Read(ip4); err != nil {
-				return nil, fmt.Errorf("read IP prefix: %w", err)
-			}
+// Entry returns the country code and CIDR list of the current entry. After Next
+// fails on corrupted binary data or a truncated Protobuf entry, it returns
+// the part of the entry read so far.
+func (r *Reader) Entry() (string, []string) {
+	return r.code, r.cidrs
+}
 
-			mask, err := r.ReadByte()
-			if err != nil {
-				return nil, fmt.Errorf("read mask: %w", err)
-			}
+// Err returns the first decoding error, or nil if the stream ended cleanly.
+// Errors in corrupted data are *geodata.DecodeError.
+func (r *Reader) Err() error {
+	if r.err == io.EOF {
+		return nil
+	}
+	return r.err
+}
+
+// detect checks the magic header and skips the binary preamble.
+func (r *Reader) detect() error {
+	header, _ := r.r.Peek(magicHeaderSize)
+	if string(header) != magicHeaderGeoIP {
+		return nil
+	}
+	r.binary = true
+	if _, err := r.r.Discard(5); err != nil {
+		return r.errorf("", -1, "%w: truncated header", ErrInvalidFormat)
+	}
+	return nil
+}
 
-			if mask <= 32 {
-				ip := net.IP(ip4)
-				cidrs = append(cidrs, fmt.Sprintf("%s/%d", ip, mask))
-			} else {
-				ip6Suffix := make([]byte, 12)
-				if _, err := r.Read(ip6Suffix); err != nil {
-					return nil, fmt.Errorf("read IPv6 suffix: %w", err)
-				}
-				ip := net.IP(append(ip4, ip6Suffix...))
-				cidrs = append(cidrs, fmt.Sprintf("%s/%d", ip, mask))
+func (r *Reader) nextBinary() (string, []string, error) {
+	if _, err := r.r.Peek(1); err != nil {
+		return "", nil, io.EOF
+	}
+
+	countryCode, err := geodata.ReadVarintString(r.r)
+	if err != nil {
+		return "", nil, r.errorf("", -1, "read country code: %w", err)
+	}
+
+	count, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		return countryCode, nil, r.errorf(countryCode, -1, "read CIDR count: %w", err)
+	}
+
+	var cidrs []string
+	for i := uint64(0); i < count; i++ {
+		ip4 := make([]byte, 4)
+		if _, err := io.ReadFull(r.r, ip4); err != nil {
+			return countryCode, cidrs, r.errorf(countryCode, int(i), "read IP prefix: %w", err)
+		}
+
+		mask, err := r.r.ReadByte()
+		if err != nil {
+			return countryCode, cidrs, r.errorf(countryCode, int(i), "read mask: %w", err)
+		}
+
+		if mask <= 32 {
+			ip := net.IP(ip4)
+			cidrs = append(cidrs, fmt.Sprintf("%s/%d", ip, mask))
+		} else {
+			ip6Suffix := make([]byte, 12)
+			if _, err := io.ReadFull(r.r, ip6Suffix); err != nil {
+				return countryCode, cidrs, r.errorf(countryCode, int(i), "read IPv6 suffix: %w", err)
 			}
+			ip := net.IP(append(ip4, ip6Suffix...))
+			cidrs = append(cidrs, fmt.Sprintf("%s/%d", ip, mask))
 		}
+	}
+
+	return countryCode, cidrs, nil
+}
 
-		result[countryCode] = cidrs
+func (r *Reader) nextProtobuf() (string, []string, error) {
+	start := r.r.Offset()
+	raw, err := geodata.ReadListEntry(r.r)
+	if err == io.EOF {
+		return "", nil, io.EOF
 	}
+	if err != nil {
+		return truncatedEntry(raw, start, err)
+	}
+	code, cidrs, err := decodeProtobufEntry(raw)
+	if err != nil {
+		return "", nil, protobufEntryError(raw, r.r.Offset()-int64(len(raw)), err)
+	}
+	return code, cidrs, nil
+}
 
-	return result, nil
+// errorf returns a *geodata.DecodeError at the current stream offset.
+func (r *Reader) errorf(tag string, index int, format string, args ...any) error {
+	return &geodata.DecodeError{Offset: r.r.Offset(), Tag: tag, Index: index, Err: fmt.Errorf(format, args...)}
+}
+
+// protobufEntryError locates the malformed part of a raw GeoIP message starting at
+// offset base and describes it as a *geodata.DecodeError.
+func protobufEntryError(raw []byte, base int64, err error) error {
+	tag, index, offset := geodata.LocateProtobufError(raw, func(record []byte) error {
+		return proto.Unmarshal(record, &router.CIDR{})
+	})
+	return &geodata.DecodeError{Offset: base + int64(offset), Tag: tag, Index: index, Err: err}
+}
+
+// truncatedEntry describes a GeoIP entry cut short by the end of the stream as a
+// *geodata.DecodeError at the entry's start offset, naming the entry and the record
+// that was cut if they were read. It also returns the entry's complete CIDRs.
+func truncatedEntry(raw []byte, start int64, err error) (string, []string, error) {
+	tag, index, _ := geodata.LocateProtobufError(raw, func(record []byte) error {
+		return proto.Unmarshal(record, &router.CIDR{})
+	})
+	key, values, decodeErr := decodeProtobufEntry(geodata.CompleteFields(raw))
+	if decodeErr != nil {
+		key, values = tag, nil
+	}
+	return key, values, &geodata.DecodeError{Offset: start, Tag: tag, Index: index, Err: fmt.Errorf("%w: %v", ErrInvalidFormat, err)}
 }
 
-func decodeProtobuf(data []byte) (map[string][]string, error) {
-	var list router.GeoIPList
-	if err := proto.Unmarshal(data, &list); err != nil {
-		return nil, ErrInvalidFormat
+// decodeProtobufEntry decodes a single raw GeoIP message.
+func decodeProtobufEntry(raw []byte) (string, []string, error) {
+	var geoip router.GeoIP
+	if err := proto.Unmarshal(raw, &geoip); err != nil {
+		return "", nil, fmt.Errorf("%w: %v", ErrInvalidFormat, err)
 	}
 
-	result := make(map[string][]string)
-	for _, geoip := range list.Entry {
-		var cidrs []string
-		for _, cidr := range geoip.Cidr {
-			ipStr := net.IP(cidr.Ip).String()
-			cidrs = append(cidrs, fmt.Sprintf("%s/%d", ipStr, cidr.Prefix))
-		}
-		result[geoip.CountryCode] = cidrs
+	var cidrs []string
+	for _, cidr := range geoip.Cidr {
+		ipStr := net.IP(cidr.Ip).String()
+		cidrs = append(cidrs, fmt.Sprintf("%s/%d", ipStr, cidr.Prefix))
 	}
+	key := geoip.CountryCode
+	if geoip.ReverseMatch {
+		key = ReverseMatchPrefix + key
+	}
+	return key, cidrs, nil
+}
 
-	return result, nil
+// hasMagicHeader reports whether data starts with the binary format signature.
+func hasMagicHeader(data []byte) bool {
+	return len(data) >= magicHeaderSize && string(data[:magicHeaderSize]) == magicHeaderGeoIP
 }
 
 // IsValid checks if the data is a valid geoip.dat file (binary or Protobuf format).
 func IsValid(data []byte) bool {
-	if len(data) >= magicHeaderSize && string(data[:magicHeaderSize]) == magicHeaderGeoIP {
+	if hasMagicHeader(data) {
 		return true
 	}
-	_, err := decodeProtobuf(data)
+	_, err := Decode(data)
 	return err == nil
 }
@@ -2,11 +2,18 @@
 package geoip
 
 import (
+	"bytes"
+	"errors"
+	"io"
 	"net"
+	"os"
+	"reflect"
 	"testing"
 
-	"dat2json/internal/geodata/router"
+	"github.com/Viktor45/dat2json/internal/geodata"
+	"github.com/Viktor45/dat2json/internal/geodata/router"
 
+	"google.golang.org/protobuf/encoding/protowire"
 	"google.golang.org/protobuf/proto"
 )
 
@@ -60,3 +67,148 @@ func TestDecodeInvalid(t *testing.T) {
 		t.Fatal("expected error")
 	}
 }
+
+func TestDecodeProtobufReverseMatch(t *testing.T) {
+	geoipList := &router.GeoIPList{
+		Entry: []*router.GeoIP{
+			{
+				CountryCode:  "CN",
+				Cidr:         []*router.CIDR{{Ip: net.IP{1, 0, 0, 0}.To4(), Prefix: 24}},
+				ReverseMatch: true,
+			},
+		},
+	}
+	data, _ := proto.Marshal(geoipList)
+	result, err := Decode(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := result["!CN"]; !ok || len(result) != 1 {
+		t.Fatalf("expected key '!CN', got %v", result)
+	}
+
+	encoded, err := Encode(result)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var list router.GeoIPList
+	if err := proto.Unmarshal(encoded, &list); err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(&list, geoipList) {
+		t.Errorf("reverse_match did not round-trip: %v", &list)
+	}
+}
+
+func TestDecodeBinaryTruncated(t *testing.T) {
+	data := []byte("GEOI\x01\x02US\x01\x01\x02\x03")
+	if _, err := Decode(data); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestDecodeErrorBinary(t *testing.T) {
+	data := []byte("GEOI\x01\x02RU\x01\x05\x00\x00\x00\x08")
+	data = append(data, "\x02US\x02\x01\x02\x03\x00\x18\x04\x04"...)
+
+	_, err := Decode(data)
+	var de *geodata.DecodeError
+	if !errors.As(err, &de) {
+		t.Fatalf("expected DecodeError, got %v", err)
+	}
+	if de.Offset != int64(len(data)) || de.Tag != "US" || de.Index != 1 {
+		t.Errorf("unexpected error location: %+v", de)
+	}
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("expected unexpected EOF cause, got %v", de.Err)
+	}
+
+	partial, err := DecodePartial(data)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	want := map[string][]string{"RU": {"5.0.0.0/8"}, "US": {"1.2.3.0/24"}}
+	if !reflect.DeepEqual(partial, want) {
+		t.Errorf("unexpected partial result: %v", partial)
+	}
+}
+
+func TestDecodeErrorProtobufTruncated(t *testing.T) {
+	first := &router.GeoIP{CountryCode: "RU", Cidr: []*router.CIDR{{Ip: net.IP{5, 0, 0, 0}.To4(), Prefix: 8}}}
+	second := &router.GeoIP{CountryCode: "US", Cidr: []*router.CIDR{
+		{Ip: net.IP{1, 2, 3, 0}.To4(), Prefix: 24},
+		{Ip: net.IP{4, 4, 4, 0}.To4(), Prefix: 24},
+	}}
+	data, err := proto.Marshal(&router.GeoIPList{Entry: []*router.GeoIP{first, second}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start, err := proto.Marshal(&router.GeoIPList{Entry: []*router.GeoIP{first}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = data[:len(data)-2]
+
+	partial, err := DecodePartial(data)
+	var de *geodata.DecodeError
+	if !errors.As(err, &de) || !errors.Is(err, ErrInvalidFormat) {
+		t.Fatalf("expected DecodeError wrapping ErrInvalidFormat, got %v", err)
+	}
+	if de.Offset != int64(len(start)) || de.Tag != "US" || de.Index != 1 {
+		t.Errorf("unexpected error location: %+v", de)
+	}
+	want := map[string][]string{"RU": {"5.0.0.0/8"}, "US": {"1.2.3.0/24"}}
+	if !reflect.DeepEqual(partial, want) {
+		t.Errorf("unexpected partial result: %v", partial)
+	}
+}
+
+func TestDecodeErrorProtobuf(t *testing.T) {
+	good, err := proto.Marshal(&router.CIDR{Ip: net.IP{1, 0, 0, 0}.To4(), Prefix: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := protowire.AppendTag(nil, 1, protowire.BytesType)
+	entry = protowire.AppendString(entry, "US")
+	entry = protowire.AppendTag(entry, 2, protowire.BytesType)
+	entry = protowire.AppendBytes(entry, good)
+	badOffset := len(entry)
+	entry = protowire.AppendTag(entry, 2, protowire.BytesType)
+	entry = protowire.AppendBytes(entry, []byte{0x0a, 0x05, 1, 2}) // IP field longer than the record
+	data := protowire.AppendTag(nil, 1, protowire.BytesType)
+	data = protowire.AppendBytes(data, entry)
+
+	_, err = Decode(data)
+	var de *geodata.DecodeError
+	if !errors.As(err, &de) {
+		t.Fatalf("expected DecodeError, got %v", err)
+	}
+	if de.Offset != int64(2+badOffset) || de.Tag != "US" || de.Index != 1 {
+		t.Errorf("unexpected error location: %+v", de)
+	}
+	if !errors.Is(err, ErrInvalidFormat) {
+		t.Errorf("expected ErrInvalidFormat cause, got %v", de.Err)
+	}
+}
+
+func TestReaderStreamsEntries(t *testing.T) {
+	data, err := os.ReadFile("../../example/zkeen-ip.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewReader(bytes.NewReader(data))
+	var codes []string
+	for r.Next() {
+		code, cidrs := r.Entry()
+		if len(cidrs) == 0 {
+			t.Errorf("%s: expected CIDRs", code)
+		}
+		codes = append(codes, code)
+	}
+	if err := r.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(codes) != 29 || codes[0] != "AKAMAI" {
+		t.Errorf("unexpected entries: %v", codes)
+	}
+}
@@ -0,0 +1,68 @@
+// internal/geoip/encode.go
+package geoip
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Viktor45/dat2json/internal/geodata/router"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Encode encodes a map of country codes to CIDR lists into a Protobuf geoip.dat file.
+// Countries are written in sorted order; CIDRs keep their order and are stored verbatim,
+// so the output of Decode round-trips byte for byte. Keys starting with ReverseMatchPrefix
+// set the reverse_match flag.
+func Encode(data map[string][]string) ([]byte, error) {
+	codes := make([]string, 0, len(data))
+	for code := range data {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool {
+		a, b := strings.TrimPrefix(codes[i], ReverseMatchPrefix), strings.TrimPrefix(codes[j], ReverseMatchPrefix)
+		if a != b {
+			return a < b
+		}
+		return codes[i] > codes[j]
+	})
+
+	list := &router.GeoIPList{Entry: make([]*router.GeoIP, 0, len(codes))}
+	for _, code := range codes {
+		countryCode, reverse := strings.CutPrefix(code, ReverseMatchPrefix)
+		entry := &router.GeoIP{CountryCode: countryCode, ReverseMatch: reverse}
+		for _, s := range data[code] {
+			cidr, err := parseCIDR(s)
+			if err != nil {
+				return nil, fmt.Errorf("country %s: %w", code, err)
+			}
+			entry.Cidr = append(entry.Cidr, cidr)
+		}
+		list.Entry = append(list.Entry, entry)
+	}
+
+	return proto.MarshalOptions{Deterministic: true}.Marshal(list)
+}
+
+// parseCIDR parses an "ip/prefix" string without masking host bits.
+func parseCIDR(s string) (*router.CIDR, error) {
+	addr, bits, ok := strings.Cut(s, "/")
+	if !ok {
+		return nil, fmt.Errorf("invalid CIDR %q: missing prefix length", s)
+	}
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid CIDR %q: bad IP address", s)
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	prefix, err := strconv.ParseUint(bits, 10, 32)
+	if err != nil || prefix > uint64(len(ip)*8) {
+		return nil, fmt.Errorf("invalid CIDR %q: bad prefix length", s)
+	}
+	return &router.CIDR{Ip: ip, Prefix: uint32(prefix)}, nil
+}
@@ -0,0 +1,49 @@
+// internal/geoip/encode_test.go
+package geoip
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestEncodeRoundTrip(t *testing.T) {
+	data, err := os.ReadFile("../../example/zkeen-ip.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := Decode(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	encoded, err := Encode(decoded)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(encoded, data) {
+		t.Errorf("round-trip mismatch: got %d bytes, want %d", len(encoded), len(data))
+	}
+}
+
+func TestEncodeIPv6(t *testing.T) {
+	encoded, err := Encode(map[string][]string{"US": {"2001:db8::/32", "1.2.3.4/24"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result, err := Decode(encoded)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cidrs := result["US"]
+	if len(cidrs) != 2 || cidrs[0] != "2001:db8::/32" || cidrs[1] != "1.2.3.4/24" {
+		t.Errorf("expected ['2001:db8::/32' '1.2.3.4/24'], got %v", cidrs)
+	}
+}
+
+func TestEncodeInvalid(t *testing.T) {
+	for _, cidr := range []string{"1.2.3.4", "bad/24", "1.2.3.4/33", "::/129"} {
+		if _, err := Encode(map[string][]string{"US": {cidr}}); err == nil {
+			t.Errorf("%s: expected error", cidr)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+// internal/geoip/expr.go
+package geoip
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/Viktor45/dat2json/internal/expr"
+
+	"go4.org/netipx"
+)
+
+// universe is the complement base: the whole IPv4 and IPv6 address space.
+var universe = []netip.Prefix{netip.MustParsePrefix("0.0.0.0/0"), netip.MustParsePrefix("::/0")}
+
+// setAlgebra evaluates expressions over the address space of country entries.
+type setAlgebra struct {
+	entries map[string][]string // keyed by upper-case country code
+}
+
+// Eval computes a set expression such as "CN - PRIVATE" or "!(RU | BY)" over the
+// country entries of data and returns the minimal CIDR list of the result.
+// Country codes are case-insensitive; reverse-match entries are not addressable
+// by name, write "!CN" (the complement of CN) instead.
+func Eval(n expr.Node, data map[string][]string) ([]string, error) {
+	a := &setAlgebra{entries: make(map[string][]string, len(data))}
+	for key, cidrs := range data {
+		a.entries[strings.ToUpper(key)] = cidrs
+	}
+	set, err := expr.Eval[*netipx.IPSet](n, a)
+	if err != nil {
+		return nil, err
+	}
+	return SetPrefixes(set), nil
+}
+
+func (a *setAlgebra) Tag(name string) (*netipx.IPSet, error) {
+	cidrs, ok := a.entries[strings.ToUpper(name)]
+	if !ok {
+		return nil, fmt.Errorf("country code '%s' not found", name)
+	}
+	set, err := NewIPSet(cidrs)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return set, nil
+}
+
+func (a *setAlgebra) Union(x, y *netipx.IPSet) (*netipx.IPSet, error) {
+	var b netipx.IPSetBuilder
+	b.AddSet(x)
+	b.AddSet(y)
+	return b.IPSet()
+}
+
+func (a *setAlgebra) Intersect(x, y *netipx.IPSet) (*netipx.IPSet, error) {
+	var b netipx.IPSetBuilder
+	b.AddSet(x)
+	b.Intersect(y)
+	return b.IPSet()
+}
+
+func (a *setAlgebra) Subtract(x, y *netipx.IPSet) (*netipx.IPSet, error) {
+	var b netipx.IPSetBuilder
+	b.AddSet(x)
+	b.RemoveSet(y)
+	return b.IPSet()
+}
+
+func (a *setAlgebra) Complement(x *netipx.IPSet) (*netipx.IPSet, error) {
+	var b netipx.IPSetBuilder
+	for _, p := range universe {
+		b.AddPrefix(p)
+	}
+	b.RemoveSet(x)
+	return b.IPSet()
+}
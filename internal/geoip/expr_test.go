@@ -0,0 +1,62 @@
+// internal/geoip/expr_test.go
+package geoip
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Viktor45/dat2json/internal/expr"
+)
+
+func TestEval(t *testing.T) {
+	data := map[string][]string{
+		"CN":      {"10.0.0.0/8", "2001:db8::/32"},
+		"PRIVATE": {"10.1.0.0/16", "192.168.0.0/16"},
+		"RU":      {"5.0.0.0/8"},
+	}
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"CN - PRIVATE", []string{"10.0.0.0/16", "10.2.0.0/15", "10.4.0.0/14", "10.8.0.0/13", "10.16.0.0/12", "10.32.0.0/11", "10.64.0.0/10", "10.128.0.0/9", "2001:db8::/32"}},
+		{"cn & private", []string{"10.1.0.0/16"}},
+		{"RU | PRIVATE", []string{"5.0.0.0/8", "10.1.0.0/16", "192.168.0.0/16"}},
+	}
+	for _, tt := range tests {
+		n, err := expr.Parse(tt.in)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got, err := Eval(n, data)
+		if err != nil {
+			t.Fatalf("Eval(%q): unexpected error: %v", tt.in, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Eval(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestEvalComplement(t *testing.T) {
+	n, err := expr.Parse("!V4")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := Eval(n, map[string][]string{"V4": {"0.0.0.0/1", "128.0.0.0/1"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, []string{"::/0"}) {
+		t.Errorf("unexpected complement: %v", got)
+	}
+}
+
+func TestEvalUnknownCountry(t *testing.T) {
+	n, err := expr.Parse("CN - XX")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := Eval(n, map[string][]string{"CN": {"10.0.0.0/8"}}); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -0,0 +1,50 @@
+// internal/geoip/family.go
+package geoip
+
+import (
+	"fmt"
+	"net/netip"
+)
+
+// Address family selectors accepted by FilterFamily.
+const (
+	FamilyIPv4 = "ipv4"
+	FamilyIPv6 = "ipv6"
+	FamilyBoth = "both"
+)
+
+// SplitFamilies partitions a CIDR list into its IPv4 and IPv6 prefixes, keeping the
+// original order within each family. IPv4-mapped IPv6 prefixes count as IPv6.
+func SplitFamilies(cidrs []string) (v4, v6 []string, err error) {
+	for _, s := range cidrs {
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid CIDR %q: %w", s, err)
+		}
+		if prefix.Addr().Is4() {
+			v4 = append(v4, s)
+		} else {
+			v6 = append(v6, s)
+		}
+	}
+	return v4, v6, nil
+}
+
+// FilterFamily returns the CIDRs of the given address family.
+func FilterFamily(cidrs []string, family string) ([]string, error) {
+	switch family {
+	case FamilyBoth:
+		return cidrs, nil
+	case FamilyIPv4, FamilyIPv6:
+		v4, v6, err := SplitFamilies(cidrs)
+		if err != nil {
+			return nil, err
+		}
+		if family == FamilyIPv4 {
+			return v4, nil
+		}
+		return v6, nil
+	default:
+		return nil, fmt.Errorf("unknown address family %q", family)
+	}
+}
@@ -0,0 +1,47 @@
+// internal/geoip/family_test.go
+package geoip
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitFamilies(t *testing.T) {
+	v4, v6, err := SplitFamilies([]string{"2001:db8::/32", "10.0.0.0/8", "::ffff:1.2.3.0/120", "192.168.0.0/16"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(v4, []string{"10.0.0.0/8", "192.168.0.0/16"}) {
+		t.Errorf("unexpected IPv4: %v", v4)
+	}
+	if !reflect.DeepEqual(v6, []string{"2001:db8::/32", "::ffff:1.2.3.0/120"}) {
+		t.Errorf("unexpected IPv6: %v", v6)
+	}
+}
+
+func TestFilterFamily(t *testing.T) {
+	cidrs := []string{"10.0.0.0/8", "2001:db8::/32"}
+	tests := []struct {
+		family string
+		want   []string
+	}{
+		{FamilyBoth, cidrs},
+		{FamilyIPv4, []string{"10.0.0.0/8"}},
+		{FamilyIPv6, []string{"2001:db8::/32"}},
+	}
+	for _, tt := range tests {
+		got, err := FilterFamily(cidrs, tt.family)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("FilterFamily(%s) = %v, want %v", tt.family, got, tt.want)
+		}
+	}
+	if _, err := FilterFamily(cidrs, "ipv5"); err == nil {
+		t.Error("expected error for unknown family")
+	}
+	if _, err := FilterFamily([]string{"bad"}, FamilyIPv4); err == nil {
+		t.Error("expected error for invalid CIDR")
+	}
+}
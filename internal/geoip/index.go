@@ -0,0 +1,110 @@
+// internal/geoip/index.go
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/Viktor45/dat2json/internal/geodata"
+	"github.com/Viktor45/dat2json/internal/geodata/router"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Index scans geoip data and records the country code and location of every entry
+// without decoding CIDRs. Country codes follow the same conventions as Decode.
+func Index(data []byte) ([]geodata.IndexEntry, error) {
+	if !hasMagicHeader(data) {
+		index, err := geodata.IndexProtobufList(data, func(entry []byte) (string, error) {
+			code, reverse, err := geodata.ProtobufFields(entry)
+			if reverse != 0 {
+				code = ReverseMatchPrefix + code
+			}
+			return code, err
+		}, func(record []byte) error {
+			return proto.Unmarshal(record, &router.CIDR{})
+		})
+		if err != nil {
+			return nil, invalidFormat(err)
+		}
+		return index, nil
+	}
+
+	if len(data) < 5 {
+		return nil, &geodata.DecodeError{Offset: int64(len(data)), Index: -1, Err: fmt.Errorf("%w: truncated header", ErrInvalidFormat)}
+	}
+	r := bytes.NewReader(data[5:])
+	var index []geodata.IndexEntry
+	for r.Len() > 0 {
+		offset := len(data) - r.Len()
+		fail := func(code string, record int, format string, err error) error {
+			return &geodata.DecodeError{Offset: int64(len(data) - r.Len()), Tag: code, Index: record, Err: fmt.Errorf(format, err)}
+		}
+		code, err := geodata.ReadVarintString(r)
+		if err != nil {
+			return nil, fail("", -1, "read country code: %w", err)
+		}
+		count, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, fail(code, -1, "read CIDR count: %w", err)
+		}
+		for i := uint64(0); i < count; i++ {
+			if err := skip(r, 4); err != nil {
+				return nil, fail(code, int(i), "read IP prefix: %w", err)
+			}
+			mask, err := r.ReadByte()
+			if err != nil {
+				return nil, fail(code, int(i), "read mask: %w", err)
+			}
+			if mask > 32 {
+				if err := skip(r, 12); err != nil {
+					return nil, fail(code, int(i), "read IPv6 suffix: %w", err)
+				}
+			}
+		}
+		index = append(index, geodata.IndexEntry{Tag: code, Offset: offset, Length: len(data) - r.Len() - offset})
+	}
+	return index, nil
+}
+
+// skip advances the reader by n bytes, failing if fewer remain.
+func skip(r *bytes.Reader, n int) error {
+	if r.Len() < n {
+		return io.ErrUnexpectedEOF
+	}
+	_, err := r.Seek(int64(n), io.SeekCurrent)
+	return err
+}
+
+// DecodeEntry decodes a single entry located by Index.
+func DecodeEntry(data []byte, entry geodata.IndexEntry) (string, []string, error) {
+	if entry.Offset < 0 || entry.Length < 0 || entry.Offset+entry.Length > len(data) {
+		return "", nil, ErrInvalidFormat
+	}
+	raw := data[entry.Offset : entry.Offset+entry.Length]
+	if !hasMagicHeader(data) {
+		code, cidrs, err := decodeProtobufEntry(raw)
+		if err != nil {
+			return "", nil, protobufEntryError(raw, int64(entry.Offset), err)
+		}
+		return code, cidrs, nil
+	}
+	r := &Reader{r: geodata.NewCountingReader(bytes.NewReader(raw), int64(entry.Offset)), started: true, binary: true}
+	code, cidrs, err := r.nextBinary()
+	if err != nil {
+		return "", nil, err
+	}
+	return code, cidrs, nil
+}
+
+// invalidFormat marks a protobuf index error as ErrInvalidFormat, keeping its location.
+func invalidFormat(err error) error {
+	var de *geodata.DecodeError
+	if errors.As(err, &de) {
+		return &geodata.DecodeError{Offset: de.Offset, Tag: de.Tag, Index: de.Index, Err: fmt.Errorf("%w: %v", ErrInvalidFormat, de.Err)}
+	}
+	return fmt.Errorf("%w: %v", ErrInvalidFormat, err)
+}
@@ -0,0 +1,87 @@
+// internal/geoip/index_test.go
+package geoip
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/Viktor45/dat2json/internal/geodata"
+)
+
+func TestIndexProtobuf(t *testing.T) {
+	data, err := os.ReadFile("../../example/zkeen-ip.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	full, err := Decode(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	index, err := Index(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(index) != len(full) {
+		t.Fatalf("expected %d entries, got %d", len(full), len(index))
+	}
+	for _, entry := range index {
+		code, cidrs, err := DecodeEntry(data, entry)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", entry.Tag, err)
+		}
+		if code != entry.Tag || !reflect.DeepEqual(cidrs, full[code]) {
+			t.Errorf("%s: entry does not match full decode", entry.Tag)
+		}
+	}
+}
+
+func TestIndexBinary(t *testing.T) {
+	data := []byte("GEOI\x01")
+	data = append(data, 0x02, 'U', 'S', 0x02)
+	data = append(data, 1, 2, 3, 0, 24)
+	data = append(data, 0x20, 0x01, 0x0d, 0xb8, 48, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0)
+	data = append(data, 0x02, 'D', 'E', 0x01, 5, 6, 7, 0, 24)
+
+	index, err := Index(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(index) != 2 || index[0].Tag != "US" || index[1].Tag != "DE" {
+		t.Fatalf("unexpected index: %+v", index)
+	}
+	code, cidrs, err := DecodeEntry(data, index[1])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if code != "DE" || len(cidrs) != 1 || cidrs[0] != "5.6.7.0/24" {
+		t.Errorf("unexpected entry: %s %v", code, cidrs)
+	}
+
+	if _, err := Index(data[:len(data)-3]); err == nil {
+		t.Error("expected error for truncated data")
+	}
+}
+
+func TestIndexProtobufErrorMatchesReader(t *testing.T) {
+	data, err := os.ReadFile("../../example/zkeen-ip.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, cut := range []int{1, 2, len(data) / 3, len(data) / 2, len(data) - 3} {
+		truncated := data[:len(data)-cut]
+		_, indexErr := Index(truncated)
+		_, decodeErr := DecodePartial(truncated)
+		var ie, de *geodata.DecodeError
+		if !errors.As(indexErr, &ie) || !errors.As(decodeErr, &de) {
+			t.Fatalf("cut %d: expected DecodeErrors, got %v and %v", cut, indexErr, decodeErr)
+		}
+		if !errors.Is(indexErr, ErrInvalidFormat) {
+			t.Errorf("cut %d: expected ErrInvalidFormat, got %v", cut, indexErr)
+		}
+		if ie.Offset != de.Offset || ie.Tag != de.Tag || ie.Index != de.Index {
+			t.Errorf("cut %d: index reports %v, reader reports %v", cut, indexErr, decodeErr)
+		}
+	}
+}
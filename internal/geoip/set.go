@@ -0,0 +1,104 @@
+// internal/geoip/set.go
+package geoip
+
+import (
+	"fmt"
+	"net/netip"
+
+	"go4.org/netipx"
+)
+
+// NewIPSet builds the address space covered by a CIDR list. Host bits are ignored.
+func NewIPSet(cidrs []string) (*netipx.IPSet, error) {
+	var b netipx.IPSetBuilder
+	for _, s := range cidrs {
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q: %w", s, err)
+		}
+		b.AddPrefix(prefix.Masked())
+	}
+	return b.IPSet()
+}
+
+// SetPrefixes returns the minimal sorted CIDR list covering the set, IPv4 before IPv6.
+func SetPrefixes(set *netipx.IPSet) []string {
+	prefixes := set.Prefixes()
+	cidrs := make([]string, 0, len(prefixes))
+	for _, p := range prefixes {
+		cidrs = append(cidrs, p.String())
+	}
+	return cidrs
+}
+
+// Diff compares two CIDR lists by the address space they cover and returns the
+// minimal CIDRs added in newer and removed from older. Reshaping the same space,
+// e.g. splitting a /23 into two /24s, produces no difference.
+func Diff(older, newer []string) (added, removed []string, err error) {
+	oldSet, err := NewIPSet(older)
+	if err != nil {
+		return nil, nil, err
+	}
+	newSet, err := NewIPSet(newer)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var b netipx.IPSetBuilder
+	b.AddSet(newSet)
+	b.RemoveSet(oldSet)
+	addedSet, err := b.IPSet()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	b = netipx.IPSetBuilder{}
+	b.AddSet(oldSet)
+	b.RemoveSet(newSet)
+	removedSet, err := b.IPSet()
+	if err != nil {
+		return nil, nil, err
+	}
+	return SetPrefixes(addedSet), SetPrefixes(removedSet), nil
+}
+
+// Aggregate normalizes a CIDR list to the minimal set of non-overlapping prefixes
+// covering the same addresses, IPv4 before IPv6, each family sorted.
+func Aggregate(cidrs []string) ([]string, error) {
+	set, err := NewIPSet(cidrs)
+	if err != nil {
+		return nil, err
+	}
+	return SetPrefixes(set), nil
+}
+
+// CountHostBits reports how many CIDRs have host bits set, such as "1.2.3.4/24".
+// Entries that do not parse as CIDRs are not counted.
+func CountHostBits(cidrs []string) int {
+	count := 0
+	for _, s := range cidrs {
+		if prefix, err := netip.ParsePrefix(s); err == nil && prefix.Masked() != prefix {
+			count++
+		}
+	}
+	return count
+}
+
+// Canonicalize masks host bits in every CIDR, e.g. "1.2.3.4/24" becomes "1.2.3.0/24",
+// and reports how many CIDRs had host bits set. Order and duplicates are preserved.
+func Canonicalize(cidrs []string) ([]string, int, error) {
+	result := make([]string, 0, len(cidrs))
+	changed := 0
+	for _, s := range cidrs {
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid CIDR %q: %w", s, err)
+		}
+		if masked := prefix.Masked(); masked != prefix {
+			prefix = masked
+			changed++
+		}
+		result = append(result, prefix.String())
+	}
+	return result, changed, nil
+}
@@ -0,0 +1,65 @@
+// internal/geoip/set_test.go
+package geoip
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffSemantic(t *testing.T) {
+	added, removed, err := Diff(
+		[]string{"10.0.0.0/23", "192.168.0.0/24", "2001:db8::/32"},
+		[]string{"10.0.1.0/24", "10.0.0.0/24", "172.16.0.0/12", "2001:db8::/33"},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(added, []string{"172.16.0.0/12"}) {
+		t.Errorf("unexpected added: %v", added)
+	}
+	if !reflect.DeepEqual(removed, []string{"192.168.0.0/24", "2001:db8:8000::/33"}) {
+		t.Errorf("unexpected removed: %v", removed)
+	}
+}
+
+func TestNewIPSetInvalid(t *testing.T) {
+	if _, err := NewIPSet([]string{"bad"}); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	got, err := Aggregate([]string{
+		"2001:db8:1::/48", "10.0.1.0/24", "10.0.0.0/24", "10.0.0.128/25",
+		"2001:db8::/48", "192.168.1.1/32", "192.168.1.0/24",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"10.0.0.0/23", "192.168.1.0/24", "2001:db8::/47"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestCountHostBits(t *testing.T) {
+	if n := CountHostBits([]string{"1.2.3.4/24", "10.0.0.0/8", "2001:db8::1/32", "bogus"}); n != 2 {
+		t.Errorf("expected 2 CIDRs with host bits, got %d", n)
+	}
+}
+
+func TestCanonicalize(t *testing.T) {
+	got, changed, err := Canonicalize([]string{"1.2.3.4/24", "10.0.0.0/8", "2001:db8::1/32", "1.2.3.0/24"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, []string{"1.2.3.0/24", "10.0.0.0/8", "2001:db8::/32", "1.2.3.0/24"}) {
+		t.Errorf("unexpected CIDRs: %v", got)
+	}
+	if changed != 2 {
+		t.Errorf("expected 2 changed CIDRs, got %d", changed)
+	}
+	if _, _, err := Canonicalize([]string{"1.2.3.0/33"}); err == nil {
+		t.Error("expected error for invalid CIDR")
+	}
+}
@@ -0,0 +1,120 @@
+// internal/geoip/table.go
+package geoip
+
+import (
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+)
+
+// Match is a country whose CIDR list contains a looked-up address, or a reverse-match
+// entry ("!CN") whose CIDR list does not.
+type Match struct {
+	Country string
+	// Prefix is the most specific CIDR of the country that contains the address.
+	// It is the zero Prefix for reverse-match entries.
+	Prefix netip.Prefix
+}
+
+// Table is a binary prefix trie over decoded geoip data that answers which
+// countries contain an address. It works on the output of Decode, so binary
+// and Protobuf inputs behave identically.
+type Table struct {
+	v4, v6  trieNode
+	reverse []reverseEntry
+}
+
+// reverseEntry is a reverse-match entry, which matches the addresses its CIDRs
+// do not contain.
+type reverseEntry struct {
+	country string
+	table   *Table
+}
+
+type trieNode struct {
+	child   [2]*trieNode
+	entries []Match
+}
+
+// NewTable builds a lookup table from a map of country codes to CIDR lists.
+// Host bits of stored CIDRs are ignored for matching. Reverse-match entries ("!CN")
+// match every address outside their CIDR list.
+func NewTable(data map[string][]string) (*Table, error) {
+	t := &Table{}
+	for country, cidrs := range data {
+		if strings.HasPrefix(country, ReverseMatchPrefix) {
+			inner, err := NewTable(map[string][]string{strings.TrimPrefix(country, ReverseMatchPrefix): cidrs})
+			if err != nil {
+				return nil, fmt.Errorf("country %s: %w", country, err)
+			}
+			t.reverse = append(t.reverse, reverseEntry{country: country, table: inner})
+			continue
+		}
+		for _, s := range cidrs {
+			prefix, err := netip.ParsePrefix(s)
+			if err != nil {
+				return nil, fmt.Errorf("country %s: %w", country, err)
+			}
+			t.insert(country, prefix)
+		}
+	}
+	sort.Slice(t.reverse, func(i, j int) bool { return t.reverse[i].country < t.reverse[j].country })
+	return t, nil
+}
+
+func (t *Table) root(addr netip.Addr) *trieNode {
+	if addr.Is4() {
+		return &t.v4
+	}
+	return &t.v6
+}
+
+func (t *Table) insert(country string, prefix netip.Prefix) {
+	masked := prefix.Masked()
+	addr := masked.Addr().AsSlice()
+	n := t.root(masked.Addr())
+	for i := 0; i < masked.Bits(); i++ {
+		bit := addr[i/8] >> (7 - i%8) & 1
+		if n.child[bit] == nil {
+			n.child[bit] = &trieNode{}
+		}
+		n = n.child[bit]
+	}
+	n.entries = append(n.entries, Match{Country: country, Prefix: prefix})
+}
+
+// Lookup returns every country containing addr, ordered from the most specific
+// matching CIDR to the least specific. Each country is reported once, with its
+// most specific CIDR. Reverse-match entries not containing addr come last.
+func (t *Table) Lookup(addr netip.Addr) []Match {
+	addr = addr.Unmap()
+	raw := addr.AsSlice()
+	n := t.root(addr)
+	var found []Match
+	for i := 0; n != nil; i++ {
+		found = append(found, n.entries...)
+		if i == len(raw)*8 {
+			break
+		}
+		n = n.child[raw[i/8]>>(7-i%8)&1]
+	}
+
+	seen := make(map[string]bool, len(found))
+	var matches []Match
+	for i := len(found) - 1; i >= 0; i-- {
+		if !seen[found[i].Country] {
+			seen[found[i].Country] = true
+			matches = append(matches, found[i])
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Prefix.Bits() > matches[j].Prefix.Bits()
+	})
+	for _, r := range t.reverse {
+		if len(r.table.Lookup(addr)) == 0 {
+			matches = append(matches, Match{Country: r.country})
+		}
+	}
+	return matches
+}
@@ -0,0 +1,87 @@
+// internal/geoip/table_test.go
+package geoip
+
+import (
+	"bytes"
+	"net/netip"
+	"testing"
+)
+
+func TestTableLookup(t *testing.T) {
+	table, err := NewTable(map[string][]string{
+		"US":      {"1.2.0.0/16", "2001:db8::/32"},
+		"EXAMPLE": {"1.2.3.4/24"},
+		"ALL":     {"0.0.0.0/0"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	matches := table.Lookup(netip.MustParseAddr("1.2.3.200"))
+	expected := []string{"EXAMPLE", "US", "ALL"}
+	if len(matches) != len(expected) {
+		t.Fatalf("expected %d matches, got %v", len(expected), matches)
+	}
+	for i, e := range expected {
+		if matches[i].Country != e {
+			t.Errorf("match[%d]: expected %s, got %s", i, e, matches[i].Country)
+		}
+	}
+	if matches[0].Prefix.String() != "1.2.3.4/24" {
+		t.Errorf("expected most specific CIDR 1.2.3.4/24, got %s", matches[0].Prefix)
+	}
+
+	if m := table.Lookup(netip.MustParseAddr("2001:db8::1")); len(m) != 1 || m[0].Country != "US" {
+		t.Errorf("unexpected IPv6 matches: %v", m)
+	}
+	if m := table.Lookup(netip.MustParseAddr("::ffff:1.2.9.9")); len(m) != 2 || m[0].Country != "US" {
+		t.Errorf("unexpected IPv4-mapped matches: %v", m)
+	}
+	if m := table.Lookup(netip.MustParseAddr("2002::1")); len(m) != 0 {
+		t.Errorf("expected no matches, got %v", m)
+	}
+}
+
+func TestTableLookupReverseMatch(t *testing.T) {
+	table, err := NewTable(map[string][]string{
+		"CN":  {"1.0.1.0/24"},
+		"!CN": {"1.0.1.0/24"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m := table.Lookup(netip.MustParseAddr("1.0.1.1")); len(m) != 1 || m[0].Country != "CN" {
+		t.Errorf("unexpected matches inside the list: %v", m)
+	}
+	m := table.Lookup(netip.MustParseAddr("8.8.8.8"))
+	if len(m) != 1 || m[0].Country != "!CN" || m[0].Prefix.IsValid() {
+		t.Errorf("unexpected matches outside the list: %v", m)
+	}
+}
+
+func TestTableBinaryAndProtobufAgree(t *testing.T) {
+	binary := []byte("GEOI\x01\x02US\x01\x01\x02\x03\x00\x18")
+	protobuf, err := Encode(map[string][]string{"US": {"1.2.3.0/24"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, data := range [][]byte{binary, protobuf} {
+		decoded, err := Decode(data)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		table, err := NewTable(decoded)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if m := table.Lookup(netip.MustParseAddr("1.2.3.4")); len(m) != 1 || m[0].Country != "US" {
+			t.Errorf("%q: unexpected matches: %v", bytes.TrimSpace(data[:4]), m)
+		}
+	}
+}
+
+func TestNewTableInvalid(t *testing.T) {
+	if _, err := NewTable(map[string][]string{"US": {"bad"}}); err == nil {
+		t.Fatal("expected error")
+	}
+}
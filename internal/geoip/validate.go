@@ -0,0 +1,78 @@
+// internal/geoip/validate.go
+package geoip
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/Viktor45/dat2json/internal/geodata"
+)
+
+// Validate checks decoded geoip data for entries routers reject or mishandle:
+// malformed CIDRs and prefix lengths beyond 32/128 are errors; host bits set,
+// duplicate prefixes, empty entries and unusual country codes are warnings.
+// Findings are ordered by country code and position.
+func Validate(data map[string][]string) []geodata.Finding {
+	var findings []geodata.Finding
+	for key, cidrs := range data {
+		add := func(severity geodata.Severity, index int, value, format string, args ...any) {
+			findings = append(findings, geodata.Finding{
+				Severity: severity,
+				Tag:      key,
+				Index:    index,
+				Value:    value,
+				Message:  fmt.Sprintf(format, args...),
+			})
+		}
+		if !geodata.ValidTag(strings.TrimPrefix(key, ReverseMatchPrefix)) {
+			add(geodata.SeverityWarning, -1, "", "unusual characters in country code")
+		}
+		if len(cidrs) == 0 {
+			add(geodata.SeverityWarning, -1, "", "entry has no CIDRs")
+		}
+		seen := make(map[netip.Prefix]int, len(cidrs))
+		for i, s := range cidrs {
+			prefix, err := parseCIDRStrict(s)
+			if err != nil {
+				add(geodata.SeverityError, i, s, "%v", err)
+				continue
+			}
+			if masked := prefix.Masked(); masked != prefix {
+				add(geodata.SeverityWarning, i, s, "host bits set (canonical form %s)", masked)
+			}
+			if j, ok := seen[prefix.Masked()]; ok {
+				add(geodata.SeverityWarning, i, s, "duplicate of [%d]", j)
+				continue
+			}
+			seen[prefix.Masked()] = i
+		}
+	}
+	geodata.SortFindings(findings)
+	return findings
+}
+
+// parseCIDRStrict parses a CIDR like netip.ParsePrefix, but explains why a value
+// is rejected, e.g. a prefix length beyond the address size.
+func parseCIDRStrict(s string) (netip.Prefix, error) {
+	addrStr, bitsStr, ok := strings.Cut(s, "/")
+	if !ok {
+		return netip.Prefix{}, fmt.Errorf("missing prefix length")
+	}
+	addr, err := netip.ParseAddr(addrStr)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid address %q", addrStr)
+	}
+	if addr.Zone() != "" {
+		return netip.Prefix{}, fmt.Errorf("address zone not allowed")
+	}
+	bits, err := strconv.Atoi(bitsStr)
+	if err != nil || bits < 0 {
+		return netip.Prefix{}, fmt.Errorf("invalid prefix length %q", bitsStr)
+	}
+	if bits > addr.BitLen() {
+		return netip.Prefix{}, fmt.Errorf("prefix length %d exceeds %d", bits, addr.BitLen())
+	}
+	return netip.PrefixFrom(addr, bits), nil
+}
@@ -0,0 +1,44 @@
+// internal/geoip/validate_test.go
+package geoip
+
+import (
+	"testing"
+
+	"github.com/Viktor45/dat2json/internal/geodata"
+)
+
+func TestValidate(t *testing.T) {
+	findings := Validate(map[string][]string{
+		"CN":    {"1.2.3.0/24", "1.2.3.4/24", "1.2.3.0/40", "2001:db8::/129", "bad", "10.0.0.0", "2001:db8::/32"},
+		"!RU":   {"5.0.0.0/8"},
+		"EMPTY": {},
+		"A B":   {"10.0.0.0/8"},
+	})
+	want := []string{
+		"warning: A B: unusual characters in country code",
+		"warning: CN[1] 1.2.3.4/24: host bits set (canonical form 1.2.3.0/24)",
+		"warning: CN[1] 1.2.3.4/24: duplicate of [0]",
+		"error: CN[2] 1.2.3.0/40: prefix length 40 exceeds 32",
+		"error: CN[3] 2001:db8::/129: prefix length 129 exceeds 128",
+		"error: CN[4] bad: missing prefix length",
+		"error: CN[5] 10.0.0.0: missing prefix length",
+		"warning: EMPTY: entry has no CIDRs",
+	}
+	if len(findings) != len(want) {
+		t.Fatalf("expected %d findings, got %d: %v", len(want), len(findings), findings)
+	}
+	for i, f := range findings {
+		if got := f.String(); got != want[i] {
+			t.Errorf("finding %d = %q, want %q", i, got, want[i])
+		}
+	}
+	if findings[3].Severity != geodata.SeverityError {
+		t.Errorf("unexpected severity: %s", findings[3].Severity)
+	}
+}
+
+func TestValidateClean(t *testing.T) {
+	if findings := Validate(map[string][]string{"US": {"8.8.8.0/24", "2001:4860::/32"}}); len(findings) != 0 {
+		t.Errorf("unexpected findings: %v", findings)
+	}
+}
@@ -0,0 +1,111 @@
+// internal/geosite/attribute.go
+package geosite
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Viktor45/dat2json/internal/geodata/router"
+)
+
+// formatAttributes renders domain attributes as a space-separated suffix such as " @cn @ads".
+// Boolean true attributes are written as "@key", other values as "@key=value";
+// an attribute without a value is written as "@key=".
+func formatAttributes(attrs []*router.Domain_Attribute) string {
+	var b strings.Builder
+	for _, attr := range attrs {
+		b.WriteString(" @")
+		b.WriteString(attr.GetKey())
+		switch v := attr.GetTypedValue().(type) {
+		case *router.Domain_Attribute_BoolValue:
+			if !v.BoolValue {
+				b.WriteString("=false")
+			}
+		case *router.Domain_Attribute_IntValue:
+			b.WriteString("=" + strconv.FormatInt(v.IntValue, 10))
+		default:
+			b.WriteString("=")
+		}
+	}
+	return b.String()
+}
+
+// parseAttribute parses a single "@key" or "@key=value" token.
+func parseAttribute(token string) (*router.Domain_Attribute, error) {
+	key, value, hasValue := strings.Cut(strings.TrimPrefix(token, "@"), "=")
+	if key == "" {
+		return nil, fmt.Errorf("empty attribute key in %q", token)
+	}
+	attr := &router.Domain_Attribute{Key: key}
+	switch {
+	case !hasValue, value == "true":
+		attr.TypedValue = &router.Domain_Attribute_BoolValue{BoolValue: true}
+	case value == "false":
+		attr.TypedValue = &router.Domain_Attribute_BoolValue{BoolValue: false}
+	case value == "":
+	default:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid attribute value in %q", token)
+		}
+		attr.TypedValue = &router.Domain_Attribute_IntValue{IntValue: n}
+	}
+	return attr, nil
+}
+
+// splitAttributes separates trailing " @attr" tokens from a rule string.
+func splitAttributes(s string) (string, []*router.Domain_Attribute, error) {
+	var attrs []*router.Domain_Attribute
+	for {
+		i := strings.LastIndex(s, " @")
+		if i < 0 || strings.Contains(s[i+1:], " ") {
+			break
+		}
+		attr, err := parseAttribute(s[i+1:])
+		if err != nil {
+			return "", nil, err
+		}
+		attrs = append(attrs, attr)
+		s = s[:i]
+	}
+	for i, j := 0, len(attrs)-1; i < j; i, j = i+1, j-1 {
+		attrs[i], attrs[j] = attrs[j], attrs[i]
+	}
+	return s, attrs, nil
+}
+
+// MatchAttributes reports whether a rule satisfies every attribute selector.
+// A selector "attr" requires the attribute to be present and "!attr" requires it to be absent;
+// keys are compared case-insensitively, as Xray does for "geosite:tag@attr".
+func MatchAttributes(rule string, selectors []string) bool {
+	_, attrs, err := splitAttributes(rule)
+	if err != nil {
+		return false
+	}
+	for _, sel := range selectors {
+		key, negate := strings.CutPrefix(sel, "!")
+		found := false
+		for _, attr := range attrs {
+			if strings.EqualFold(attr.GetKey(), key) {
+				found = true
+				break
+			}
+		}
+		if found == negate {
+			return false
+		}
+	}
+	return true
+}
+
+// FilterByAttributes returns the rules that satisfy every attribute selector.
+func FilterByAttributes(rules []string, selectors []string) []string {
+	var result []string
+	for _, rule := range rules {
+		if MatchAttributes(rule, selectors) {
+			result = append(result, rule)
+		}
+	}
+	return result
+}
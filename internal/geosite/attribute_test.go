@@ -0,0 +1,43 @@
+// internal/geosite/attribute_test.go
+package geosite
+
+import "testing"
+
+func TestFilterByAttributes(t *testing.T) {
+	rules := []string{
+		"domain:google.com",
+		"domain:google.cn @cn",
+		"full:ads.google.cn @cn @ads",
+	}
+	tests := []struct {
+		selectors []string
+		expected  int
+	}{
+		{nil, 3},
+		{[]string{"cn"}, 2},
+		{[]string{"CN"}, 2},
+		{[]string{"!cn"}, 1},
+		{[]string{"cn", "!ads"}, 1},
+		{[]string{"ads"}, 1},
+		{[]string{"missing"}, 0},
+	}
+	for _, tt := range tests {
+		got := FilterByAttributes(rules, tt.selectors)
+		if len(got) != tt.expected {
+			t.Errorf("%v: expected %d rules, got %v", tt.selectors, tt.expected, got)
+		}
+	}
+}
+
+func TestSplitAttributes(t *testing.T) {
+	rule, attrs, err := splitAttributes("regexp:^a b$ @cn @rank=2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rule != "regexp:^a b$" {
+		t.Errorf("expected rule 'regexp:^a b$', got %q", rule)
+	}
+	if len(attrs) != 2 || attrs[0].GetKey() != "cn" || attrs[1].GetIntValue() != 2 {
+		t.Errorf("unexpected attributes: %v", attrs)
+	}
+}
@@ -5,9 +5,10 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
+	"io"
 
-	"dat2json/internal/geodata"
-	"dat2json/internal/geodata/router"
+	"github.com/Viktor45/dat2json/internal/geodata"
+	"github.com/Viktor45/dat2json/internal/geodata/router"
 
 	"google.golang.org/protobuf/proto"
 )
@@ -21,11 +22,105 @@ const (
 var ErrInvalidFormat = fmt.Errorf("not a valid geosite.dat file")
 
 // Decode decodes binary or Protobuf geosite data into a map of tags to domain lists.
+// Domain attributes are appended to each rule as " @key" tokens, e.g. "domain:google.cn @cn".
+// Errors in corrupted data are returned as *geodata.DecodeError.
 func Decode(data []byte) (map[string][]string, error) {
-	if len(data) >= magicHeaderSize && string(data[:magicHeaderSize]) == magicHeaderGeoSite {
-		return decodeBinary(data)
+	result, err := DecodePartial(data)
+	if err != nil {
+		return nil, err
 	}
-	return decodeProtobuf(data)
+	return result, nil
+}
+
+// DecodePartial is like Decode, but on corrupted data it returns everything decoded
+// before the corruption point along with the error, including the rules read so far
+// from the entry that failed.
+func DecodePartial(data []byte) (map[string][]string, error) {
+	r := NewReader(bytes.NewReader(data))
+	result := make(map[string][]string)
+	for r.Next() {
+		tag, domains := r.Entry()
+		result[tag] = domains
+	}
+	if err := r.Err(); err != nil {
+		if tag, domains := r.Entry(); tag != "" && len(domains) > 0 {
+			if _, ok := result[tag]; !ok {
+				result[tag] = domains
+			}
+		}
+		return result, err
+	}
+	return result, nil
+}
+
+// Reader decodes a geosite.dat stream one tag at a time, so memory use is bounded
+// by the largest entry rather than the whole file.
+type Reader struct {
+	r       *geodata.CountingReader
+	started bool
+	binary  bool
+	tag     string
+	domains []string
+	err     error
+}
+
+// NewReader returns a Reader that decodes binary or Protobuf geosite data from r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: geodata.NewCountingReader(r, 0)}
+}
+
+// Next advances to the next entry and reports whether one is available.
+// After Next returns false, Err reports any decoding error.
+func (r *Reader) Next() bool {
+	if r.err != nil {
+		return false
+	}
+	if !r.started {
+		r.started = true
+		if r.err = r.detect(); r.err != nil {
+			return false
+		}
+	}
+	var err error
+	if r.binary {
+		r.tag, r.domains, err = r.nextBinary()
+	} else {
+		r.tag, r.domains, err = r.nextProtobuf()
+	}
+	if err != nil {
+		r.err = err
+		return false
+	}
+	return true
+}
+
+// Entry returns the tag and domain rules of the current entry. After Next fails
+// on corrupted binary data or a truncated Protobuf entry, it returns the part of
+// the entry read so far.
+func (r *Reader) Entry() (string, []string) {
+	return r.tag, r.domains
+}
+
+// Err returns the first decoding error, or nil if the stream ended cleanly.
+// Errors in corrupted data are *geodata.DecodeError.
+func (r *Reader) Err() error {
+	if r.err == io.EOF {
+		return nil
+	}
+	return r.err
+}
+
+// detect checks the magic header and skips the binary preamble.
+func (r *Reader) detect() error {
+	header, _ := r.r.Peek(magicHeaderSize)
+	if string(header) != magicHeaderGeoSite {
+		return nil
+	}
+	r.binary = true
+	if _, err := r.r.Discard(5); err != nil {
+		return r.errorf("", -1, "%w: truncated header", ErrInvalidFormat)
+	}
+	return nil
 }
 
 // domainTypePrefix returns the prefix string for a given domain type byte.
@@ -44,43 +139,37 @@ func domainTypePrefix(domainType byte) string {
 	}
 }
 
-func decodeBinary(data []byte) (map[string][]string, error) {
-	if len(data) < 5 {
-		return nil, ErrInvalidFormat
+func (r *Reader) nextBinary() (string, []string, error) {
+	if _, err := r.r.Peek(1); err != nil {
+		return "", nil, io.EOF
 	}
-	r := bytes.NewReader(data[5:])
-	result := make(map[string][]string)
 
-	for r.Len() > 0 {
-		tagName, err := geodata.ReadVarintString(r)
-		if err != nil {
-			return nil, fmt.Errorf("read tag name: %w", err)
-		}
+	tagName, err := geodata.ReadVarintString(r.r)
+	if err != nil {
+		return "", nil, r.errorf("", -1, "read tag name: %w", err)
+	}
 
-		count, err := binary.ReadUvarint(r)
+	count, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		return tagName, nil, r.errorf(tagName, -1, "read domain count: %w", err)
+	}
+
+	var domains []string
+	for i := uint64(0); i < count; i++ {
+		domainType, err := r.r.ReadByte()
 		if err != nil {
-			return nil, fmt.Errorf("read domain count: %w", err)
+			return tagName, domains, r.errorf(tagName, int(i), "read domain type: %w", err)
 		}
 
-		var domains []string
-		for i := uint64(0); i < count; i++ {
-			domainType, err := r.ReadByte()
-			if err != nil {
-				return nil, fmt.Errorf("read domain type: %w", err)
-			}
-
-			value, err := geodata.ReadVarintString(r)
-			if err != nil {
-				return nil, fmt.Errorf("read domain value: %w", err)
-			}
-
-			domains = append(domains, domainTypePrefix(domainType)+value)
+		value, err := geodata.ReadVarintString(r.r)
+		if err != nil {
+			return tagName, domains, r.errorf(tagName, int(i), "read domain value: %w", err)
 		}
 
-		result[tagName] = domains
+		domains = append(domains, domainTypePrefix(domainType)+value)
 	}
 
-	return result, nil
+	return tagName, domains, nil
 }
 
 // protobufDomainTypePrefix returns the prefix for Protobuf router.Domain types.
@@ -99,29 +188,74 @@ func protobufDomainTypePrefix(t router.Domain_Type) string {
 	}
 }
 
-func decodeProtobuf(data []byte) (map[string][]string, error) {
-	var list router.GeoSiteList
-	if err := proto.Unmarshal(data, &list); err != nil {
-		return nil, ErrInvalidFormat
+func (r *Reader) nextProtobuf() (string, []string, error) {
+	start := r.r.Offset()
+	raw, err := geodata.ReadListEntry(r.r)
+	if err == io.EOF {
+		return "", nil, io.EOF
+	}
+	if err != nil {
+		return truncatedEntry(raw, start, err)
+	}
+	tag, domains, err := decodeProtobufEntry(raw)
+	if err != nil {
+		return "", nil, protobufEntryError(raw, r.r.Offset()-int64(len(raw)), err)
+	}
+	return tag, domains, nil
+}
+
+// errorf returns a *geodata.DecodeError at the current stream offset.
+func (r *Reader) errorf(tag string, index int, format string, args ...any) error {
+	return &geodata.DecodeError{Offset: r.r.Offset(), Tag: tag, Index: index, Err: fmt.Errorf(format, args...)}
+}
+
+// protobufEntryError locates the malformed part of a raw GeoSite message starting at
+// offset base and describes it as a *geodata.DecodeError.
+func protobufEntryError(raw []byte, base int64, err error) error {
+	tag, index, offset := geodata.LocateProtobufError(raw, func(record []byte) error {
+		return proto.Unmarshal(record, &router.Domain{})
+	})
+	return &geodata.DecodeError{Offset: base + int64(offset), Tag: tag, Index: index, Err: err}
+}
+
+// truncatedEntry describes a GeoSite entry cut short by the end of the stream as a
+// *geodata.DecodeError at the entry's start offset, naming the entry and the record
+// that was cut if they were read. It also returns the entry's complete domain rules.
+func truncatedEntry(raw []byte, start int64, err error) (string, []string, error) {
+	tag, index, _ := geodata.LocateProtobufError(raw, func(record []byte) error {
+		return proto.Unmarshal(record, &router.Domain{})
+	})
+	key, values, decodeErr := decodeProtobufEntry(geodata.CompleteFields(raw))
+	if decodeErr != nil {
+		key, values = tag, nil
+	}
+	return key, values, &geodata.DecodeError{Offset: start, Tag: tag, Index: index, Err: fmt.Errorf("%w: %v", ErrInvalidFormat, err)}
+}
+
+// decodeProtobufEntry decodes a single raw GeoSite message.
+func decodeProtobufEntry(raw []byte) (string, []string, error) {
+	var site router.GeoSite
+	if err := proto.Unmarshal(raw, &site); err != nil {
+		return "", nil, fmt.Errorf("%w: %v", ErrInvalidFormat, err)
 	}
 
-	result := make(map[string][]string)
-	for _, site := range list.Entry {
-		var domains []string
-		for _, d := range site.Domain {
-			domains = append(domains, protobufDomainTypePrefix(d.GetType())+d.GetValue())
-		}
-		result[site.CountryCode] = domains
+	var domains []string
+	for _, d := range site.Domain {
+		domains = append(domains, protobufDomainTypePrefix(d.GetType())+d.GetValue()+formatAttributes(d.GetAttribute()))
 	}
+	return site.CountryCode, domains, nil
+}
 
-	return result, nil
+// hasMagicHeader reports whether data starts with the binary format signature.
+func hasMagicHeader(data []byte) bool {
+	return len(data) >= magicHeaderSize && string(data[:magicHeaderSize]) == magicHeaderGeoSite
 }
 
 // IsValid checks if the data is a valid geosite.dat file (binary or Protobuf format).
 func IsValid(data []byte) bool {
-	if len(data) >= magicHeaderSize && string(data[:magicHeaderSize]) == magicHeaderGeoSite {
+	if hasMagicHeader(data) {
 		return true
 	}
-	_, err := decodeProtobuf(data)
+	_, err := Decode(data)
 	return err == nil
 }
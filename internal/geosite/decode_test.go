@@ -2,9 +2,15 @@
 package geosite
 
 import (
+	"bytes"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
 	"testing"
 
-	"dat2json/internal/geodata/router"
+	"github.com/Viktor45/dat2json/internal/geodata"
+	"github.com/Viktor45/dat2json/internal/geodata/router"
 
 	"google.golang.org/protobuf/proto"
 )
@@ -65,3 +71,122 @@ func TestDecodeInvalid(t *testing.T) {
 		t.Fatal("expected error")
 	}
 }
+
+func TestDecodeErrorBinary(t *testing.T) {
+	data := []byte("GEOS\x01\x03ads\x02\x00\x05a.com\x01\x09b.c")
+
+	_, err := Decode(data)
+	var de *geodata.DecodeError
+	if !errors.As(err, &de) {
+		t.Fatalf("expected DecodeError, got %v", err)
+	}
+	if de.Offset != int64(len(data)) || de.Tag != "ads" || de.Index != 1 {
+		t.Errorf("unexpected error location: %+v", de)
+	}
+	if !strings.Contains(err.Error(), `tag "ads": record 1: read domain value`) {
+		t.Errorf("unexpected message: %v", err)
+	}
+
+	partial, err := DecodePartial(data)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if want := map[string][]string{"ads": {"domain:a.com"}}; !reflect.DeepEqual(partial, want) {
+		t.Errorf("unexpected partial result: %v", partial)
+	}
+}
+
+func TestDecodeErrorProtobufTruncated(t *testing.T) {
+	first := &router.GeoSite{CountryCode: "a", Domain: []*router.Domain{{Type: router.Domain_Full, Value: "a.com"}}}
+	data, err := proto.Marshal(&router.GeoSiteList{Entry: []*router.GeoSite{
+		first,
+		{CountryCode: "b", Domain: []*router.Domain{{Type: router.Domain_Full, Value: "b.com"}}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start, err := proto.Marshal(&router.GeoSiteList{Entry: []*router.GeoSite{first}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = data[:len(data)-3]
+
+	partial, err := DecodePartial(data)
+	var de *geodata.DecodeError
+	if !errors.As(err, &de) || !errors.Is(err, ErrInvalidFormat) {
+		t.Fatalf("expected DecodeError wrapping ErrInvalidFormat, got %v", err)
+	}
+	if de.Offset != int64(len(start)) || de.Tag != "b" || de.Index != 0 {
+		t.Errorf("unexpected error location: %+v", de)
+	}
+	if want := map[string][]string{"a": {"full:a.com"}}; !reflect.DeepEqual(partial, want) {
+		t.Errorf("unexpected partial result: %v", partial)
+	}
+}
+
+func TestDecodeProtobufAttributes(t *testing.T) {
+	geositeList := &router.GeoSiteList{
+		Entry: []*router.GeoSite{
+			{
+				CountryCode: "google",
+				Domain: []*router.Domain{
+					{
+						Type:  router.Domain_Domain,
+						Value: "google.cn",
+						Attribute: []*router.Domain_Attribute{
+							{Key: "cn", TypedValue: &router.Domain_Attribute_BoolValue{BoolValue: true}},
+							{Key: "ads", TypedValue: &router.Domain_Attribute_BoolValue{BoolValue: false}},
+							{Key: "rank", TypedValue: &router.Domain_Attribute_IntValue{IntValue: 3}},
+						},
+					},
+				},
+			},
+		},
+	}
+	data, _ := proto.Marshal(geositeList)
+	result, err := Decode(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "domain:google.cn @cn @ads=false @rank=3"
+	if domains := result["google"]; len(domains) != 1 || domains[0] != expected {
+		t.Errorf("expected [%q], got %v", expected, domains)
+	}
+
+	encoded, err := Encode(result)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !proto.Equal(mustUnmarshal(t, encoded), geositeList) {
+		t.Errorf("attributes did not round-trip")
+	}
+}
+
+func mustUnmarshal(t *testing.T, data []byte) *router.GeoSiteList {
+	t.Helper()
+	var list router.GeoSiteList
+	if err := proto.Unmarshal(data, &list); err != nil {
+		t.Fatal(err)
+	}
+	return &list
+}
+
+func TestReaderStreamsEntries(t *testing.T) {
+	data, err := os.ReadFile("../../example/zkeen-site.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewReader(bytes.NewReader(data))
+	var tags []string
+	for r.Next() {
+		tag, _ := r.Entry()
+		tags = append(tags, tag)
+	}
+	if err := r.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"BYPASS", "CN", "DOMAINS", "OTHER", "POLITIC", "YOUTUBE"}
+	if strings.Join(tags, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected %v, got %v", expected, tags)
+	}
+}
@@ -0,0 +1,26 @@
+// internal/geosite/diff.go
+package geosite
+
+// Diff compares two rule lists and returns the rules only present in newer (added)
+// and only present in older (removed), each in their original order.
+func Diff(older, newer []string) (added, removed []string) {
+	oldSet := make(map[string]bool, len(older))
+	for _, r := range older {
+		oldSet[r] = true
+	}
+	newSet := make(map[string]bool, len(newer))
+	for _, r := range newer {
+		newSet[r] = true
+		if !oldSet[r] {
+			added = append(added, r)
+			oldSet[r] = true
+		}
+	}
+	for _, r := range older {
+		if !newSet[r] {
+			removed = append(removed, r)
+			newSet[r] = true
+		}
+	}
+	return added, removed
+}
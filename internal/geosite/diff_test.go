@@ -0,0 +1,20 @@
+// internal/geosite/diff_test.go
+package geosite
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	added, removed := Diff(
+		[]string{"domain:a.com", "full:b.com", "full:b.com"},
+		[]string{"full:b.com", "keyword:c", "keyword:c"},
+	)
+	if !reflect.DeepEqual(added, []string{"keyword:c"}) {
+		t.Errorf("unexpected added: %v", added)
+	}
+	if !reflect.DeepEqual(removed, []string{"domain:a.com"}) {
+		t.Errorf("unexpected removed: %v", removed)
+	}
+}
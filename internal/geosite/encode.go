@@ -0,0 +1,76 @@
+// internal/geosite/encode.go
+package geosite
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Viktor45/dat2json/internal/geodata/router"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Encode encodes a map of tags to prefixed domain rules into a Protobuf geosite.dat file.
+// Tags are written in sorted order; rules keep their order, so the output of Decode
+// round-trips byte for byte.
+func Encode(data map[string][]string) ([]byte, error) {
+	tags := make([]string, 0, len(data))
+	for tag := range data {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	list := &router.GeoSiteList{Entry: make([]*router.GeoSite, 0, len(tags))}
+	for _, tag := range tags {
+		entry := &router.GeoSite{CountryCode: tag}
+		for _, s := range data[tag] {
+			domain, err := ParseRule(s)
+			if err != nil {
+				return nil, fmt.Errorf("tag %s: %w", tag, err)
+			}
+			entry.Domain = append(entry.Domain, domain)
+		}
+		list.Entry = append(list.Entry, entry)
+	}
+
+	return proto.MarshalOptions{Deterministic: true}.Marshal(list)
+}
+
+// parseDomainType converts a rule prefix (without the trailing colon) to a Protobuf domain type.
+func parseDomainType(prefix string) (router.Domain_Type, bool) {
+	switch prefix {
+	case "domain":
+		return router.Domain_Domain, true
+	case "full":
+		return router.Domain_Full, true
+	case "regexp":
+		return router.Domain_Regex, true
+	case "keyword":
+		return router.Domain_Plain, true
+	}
+	if n, ok := strings.CutPrefix(prefix, "type"); ok {
+		if v, err := strconv.ParseInt(n, 10, 32); err == nil && v >= 0 {
+			return router.Domain_Type(v), true
+		}
+	}
+	return 0, false
+}
+
+// ParseRule parses a prefixed rule such as "domain:example.com @cn" into a router.Domain.
+func ParseRule(s string) (*router.Domain, error) {
+	rule, attrs, err := splitAttributes(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rule %q: %w", s, err)
+	}
+	prefix, value, ok := strings.Cut(rule, ":")
+	if !ok {
+		return nil, fmt.Errorf("invalid rule %q: missing type prefix", s)
+	}
+	t, ok := parseDomainType(prefix)
+	if !ok {
+		return nil, fmt.Errorf("invalid rule %q: unknown type prefix %q", s, prefix)
+	}
+	return &router.Domain{Type: t, Value: value, Attribute: attrs}, nil
+}
@@ -0,0 +1,70 @@
+// internal/geosite/encode_test.go
+package geosite
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/Viktor45/dat2json/internal/geodata/router"
+
+	"google.golang.org/protobuf/proto"
+)
+
+func TestEncodeRoundTrip(t *testing.T) {
+	data, err := os.ReadFile("../../example/zkeen-site.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := Decode(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	encoded, err := Encode(decoded)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(encoded, data) {
+		t.Errorf("round-trip mismatch: got %d bytes, want %d", len(encoded), len(data))
+	}
+}
+
+func TestEncodeTypes(t *testing.T) {
+	rules := []string{"domain:example.com", "full:www.example.com", "regexp:^a:b$", "keyword:ads", "type7:odd"}
+	encoded, err := Encode(map[string][]string{"test": rules})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var list router.GeoSiteList
+	if err := proto.Unmarshal(encoded, &list); err != nil {
+		t.Fatal(err)
+	}
+	expected := []router.Domain_Type{router.Domain_Domain, router.Domain_Full, router.Domain_Regex, router.Domain_Plain, 7}
+	for i, d := range list.Entry[0].Domain {
+		if d.Type != expected[i] {
+			t.Errorf("domain[%d]: expected type %v, got %v", i, expected[i], d.Type)
+		}
+	}
+	if v := list.Entry[0].Domain[2].Value; v != "^a:b$" {
+		t.Errorf("expected regexp value '^a:b$', got %q", v)
+	}
+
+	result, err := Decode(encoded)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i, r := range rules {
+		if result["test"][i] != r {
+			t.Errorf("rule[%d]: expected %q, got %q", i, r, result["test"][i])
+		}
+	}
+}
+
+func TestEncodeInvalid(t *testing.T) {
+	for _, rule := range []string{"example.com", "suffix:example.com", "type-1:x", "domain:x.com @", "domain:x.com @a=b"} {
+		if _, err := Encode(map[string][]string{"test": {rule}}); err == nil {
+			t.Errorf("%s: expected error", rule)
+		}
+	}
+}
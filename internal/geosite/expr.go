@@ -0,0 +1,159 @@
+// internal/geosite/expr.go
+package geosite
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Viktor45/dat2json/internal/expr"
+	"github.com/Viktor45/dat2json/internal/geodata/router"
+)
+
+// ErrComplement is returned for "!" in geosite expressions: the set of all
+// hostnames outside a tag cannot be written as rules.
+var ErrComplement = errors.New("complement is not supported for geosite rules")
+
+// setAlgebra evaluates expressions over the rule lists of geosite tags.
+type setAlgebra struct {
+	entries map[string][]string // keyed by lower-case tag
+	notes   []string
+	noted   map[string]bool
+}
+
+// Eval computes a set expression such as "geolocation-!cn - google" over the tags of
+// data and returns the resulting rules. Results are pruned so that rules subsumed by
+// a "domain:" rule with the same attributes are dropped. Tags are case-insensitive.
+//
+// Union, subtraction and intersection are exact for "domain:" and "full:" rules.
+// "keyword:" and "regexp:" rules, and "domain:" rules only partly covered by a
+// subtracted rule, cannot be reasoned about precisely; they are kept as they are and
+// described in the returned notes.
+func Eval(n expr.Node, data map[string][]string) (rules, notes []string, err error) {
+	a := &setAlgebra{
+		entries: make(map[string][]string, len(data)),
+		noted:   make(map[string]bool),
+	}
+	for tag, tagRules := range data {
+		a.entries[strings.ToLower(tag)] = tagRules
+	}
+	rules, err = expr.Eval[[]string](n, a)
+	if err != nil {
+		return nil, nil, err
+	}
+	rules, _, err = Prune(rules)
+	if err != nil {
+		return nil, nil, err
+	}
+	return rules, a.notes, nil
+}
+
+func (a *setAlgebra) note(format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	if !a.noted[msg] {
+		a.noted[msg] = true
+		a.notes = append(a.notes, msg)
+	}
+}
+
+func (a *setAlgebra) Tag(name string) ([]string, error) {
+	rules, ok := a.entries[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("tag '%s' not found", name)
+	}
+	return rules, nil
+}
+
+func (a *setAlgebra) Union(x, y []string) ([]string, error) {
+	rules := make([]string, 0, len(x)+len(y))
+	rules = append(rules, x...)
+	rules = append(rules, y...)
+	rules, _, err := Prune(rules)
+	return rules, err
+}
+
+func (a *setAlgebra) Subtract(x, y []string) ([]string, error) {
+	px, py, iy, err := parseOperands(x, y)
+	if err != nil {
+		return nil, err
+	}
+	all := func(int) bool { return true }
+	var result []string
+	kept := make(map[string]string) // "domain:" values kept from x, for partial overlap notes
+	for _, r := range px {
+		if iy.coveredBy(r, all) {
+			continue
+		}
+		result = append(result, r.raw)
+		if !r.exact() && len(py) > 0 {
+			a.note("%s kept: its overlap with the subtracted rules cannot be determined", r.raw)
+		}
+		if _, seen := kept[r.value]; r.typ == router.Domain_Domain && !seen {
+			kept[r.value] = r.raw
+		}
+	}
+	for _, s := range py {
+		if !s.exact() {
+			a.note("%s cannot be subtracted precisely", s.raw)
+			continue
+		}
+		name := s.value
+		if raw, ok := kept[name]; ok {
+			a.note("%s only partly removed by %s", raw, s.raw)
+		}
+		for i := strings.IndexByte(name, '.'); i >= 0; i = strings.IndexByte(name, '.') {
+			name = name[i+1:]
+			if raw, ok := kept[name]; ok {
+				a.note("%s only partly removed by %s", raw, s.raw)
+			}
+		}
+	}
+	return result, nil
+}
+
+func (a *setAlgebra) Intersect(x, y []string) ([]string, error) {
+	px, py, iy, err := parseOperands(x, y)
+	if err != nil {
+		return nil, err
+	}
+	ix, err := newRuleIndex(px)
+	if err != nil {
+		return nil, err
+	}
+	all := func(int) bool { return true }
+	var result []string
+	for _, pair := range []struct {
+		rules []parsedRule
+		other *ruleIndex
+	}{{px, iy}, {py, ix}} {
+		for _, r := range pair.rules {
+			if pair.other.coveredBy(r, all) {
+				result = append(result, r.raw)
+			} else if !r.exact() {
+				a.note("%s cannot be intersected precisely", r.raw)
+			}
+		}
+	}
+	return result, nil
+}
+
+func (a *setAlgebra) Complement([]string) ([]string, error) {
+	return nil, ErrComplement
+}
+
+// parseOperands parses both operands of a binary operation and indexes the second.
+func parseOperands(x, y []string) ([]parsedRule, []parsedRule, *ruleIndex, error) {
+	px, err := parseRules(x)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	py, err := parseRules(y)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	iy, err := newRuleIndex(py)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return px, py, iy, nil
+}
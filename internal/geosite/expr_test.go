@@ -0,0 +1,71 @@
+// internal/geosite/expr_test.go
+package geosite
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/Viktor45/dat2json/internal/expr"
+)
+
+func TestEval(t *testing.T) {
+	data := map[string][]string{
+		"geolocation-!cn": {"domain:google.com", "full:www.youtube.com", "domain:github.com", "keyword:porn"},
+		"GOOGLE":          {"domain:google.com", "full:mail.google.com", "domain:youtube.com"},
+		"dev":             {"domain:api.github.com", "full:google.com"},
+	}
+	tests := []struct {
+		in        string
+		want      []string
+		wantNotes []string
+	}{
+		{
+			in:   "google | dev",
+			want: []string{"domain:google.com", "domain:youtube.com", "domain:api.github.com"},
+		},
+		{
+			in:        "geolocation-!cn - google",
+			want:      []string{"domain:github.com", "keyword:porn"},
+			wantNotes: []string{"keyword:porn kept: its overlap with the subtracted rules cannot be determined"},
+		},
+		{
+			in:        "geolocation-!cn & (google | dev)",
+			want:      []string{"domain:google.com", "full:www.youtube.com", "domain:api.github.com"},
+			wantNotes: []string{"keyword:porn cannot be intersected precisely"},
+		},
+		{
+			in:        "geolocation-!cn - dev",
+			want:      []string{"domain:google.com", "full:www.youtube.com", "domain:github.com", "keyword:porn"},
+			wantNotes: []string{"keyword:porn kept: its overlap with the subtracted rules cannot be determined", "domain:github.com only partly removed by domain:api.github.com", "domain:google.com only partly removed by full:google.com"},
+		},
+	}
+	for _, tt := range tests {
+		n, err := expr.Parse(tt.in)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got, notes, err := Eval(n, data)
+		if err != nil {
+			t.Fatalf("Eval(%q): unexpected error: %v", tt.in, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Eval(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+		if !reflect.DeepEqual(notes, tt.wantNotes) {
+			t.Errorf("Eval(%q) notes = %q, want %q", tt.in, notes, tt.wantNotes)
+		}
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	data := map[string][]string{"google": {"domain:google.com"}}
+	n, _ := expr.Parse("!google")
+	if _, _, err := Eval(n, data); !errors.Is(err, ErrComplement) {
+		t.Errorf("expected ErrComplement, got %v", err)
+	}
+	n, _ = expr.Parse("google - missing")
+	if _, _, err := Eval(n, data); err == nil {
+		t.Error("expected error for unknown tag")
+	}
+}
@@ -0,0 +1,99 @@
+// internal/geosite/index.go
+package geosite
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/Viktor45/dat2json/internal/geodata"
+	"github.com/Viktor45/dat2json/internal/geodata/router"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Index scans geosite data and records the tag and location of every entry
+// without decoding domain rules.
+func Index(data []byte) ([]geodata.IndexEntry, error) {
+	if !hasMagicHeader(data) {
+		index, err := geodata.IndexProtobufList(data, func(entry []byte) (string, error) {
+			tag, _, err := geodata.ProtobufFields(entry)
+			return tag, err
+		}, func(record []byte) error {
+			return proto.Unmarshal(record, &router.Domain{})
+		})
+		if err != nil {
+			return nil, invalidFormat(err)
+		}
+		return index, nil
+	}
+
+	if len(data) < 5 {
+		return nil, &geodata.DecodeError{Offset: int64(len(data)), Index: -1, Err: fmt.Errorf("%w: truncated header", ErrInvalidFormat)}
+	}
+	r := bytes.NewReader(data[5:])
+	var index []geodata.IndexEntry
+	for r.Len() > 0 {
+		offset := len(data) - r.Len()
+		fail := func(tag string, record int, format string, err error) error {
+			return &geodata.DecodeError{Offset: int64(len(data) - r.Len()), Tag: tag, Index: record, Err: fmt.Errorf(format, err)}
+		}
+		tag, err := geodata.ReadVarintString(r)
+		if err != nil {
+			return nil, fail("", -1, "read tag name: %w", err)
+		}
+		count, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, fail(tag, -1, "read domain count: %w", err)
+		}
+		for i := uint64(0); i < count; i++ {
+			if _, err := r.ReadByte(); err != nil {
+				return nil, fail(tag, int(i), "read domain type: %w", err)
+			}
+			length, err := binary.ReadUvarint(r)
+			if err != nil {
+				return nil, fail(tag, int(i), "read domain value: %w", err)
+			}
+			if length > uint64(r.Len()) {
+				return nil, fail(tag, int(i), "read domain value: %w", errors.New("string too long"))
+			}
+			if _, err := r.Seek(int64(length), io.SeekCurrent); err != nil {
+				return nil, fail(tag, int(i), "read domain value: %w", err)
+			}
+		}
+		index = append(index, geodata.IndexEntry{Tag: tag, Offset: offset, Length: len(data) - r.Len() - offset})
+	}
+	return index, nil
+}
+
+// DecodeEntry decodes a single entry located by Index.
+func DecodeEntry(data []byte, entry geodata.IndexEntry) (string, []string, error) {
+	if entry.Offset < 0 || entry.Length < 0 || entry.Offset+entry.Length > len(data) {
+		return "", nil, ErrInvalidFormat
+	}
+	raw := data[entry.Offset : entry.Offset+entry.Length]
+	if !hasMagicHeader(data) {
+		tag, domains, err := decodeProtobufEntry(raw)
+		if err != nil {
+			return "", nil, protobufEntryError(raw, int64(entry.Offset), err)
+		}
+		return tag, domains, nil
+	}
+	r := &Reader{r: geodata.NewCountingReader(bytes.NewReader(raw), int64(entry.Offset)), started: true, binary: true}
+	tag, domains, err := r.nextBinary()
+	if err != nil {
+		return "", nil, err
+	}
+	return tag, domains, nil
+}
+
+// invalidFormat marks a protobuf index error as ErrInvalidFormat, keeping its location.
+func invalidFormat(err error) error {
+	var de *geodata.DecodeError
+	if errors.As(err, &de) {
+		return &geodata.DecodeError{Offset: de.Offset, Tag: de.Tag, Index: de.Index, Err: fmt.Errorf("%w: %v", ErrInvalidFormat, de.Err)}
+	}
+	return fmt.Errorf("%w: %v", ErrInvalidFormat, err)
+}
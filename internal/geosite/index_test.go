@@ -0,0 +1,81 @@
+// internal/geosite/index_test.go
+package geosite
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/Viktor45/dat2json/internal/geodata"
+)
+
+func TestIndexProtobuf(t *testing.T) {
+	data, err := os.ReadFile("../../example/zkeen-site.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	full, err := Decode(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	index, err := Index(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(index) != len(full) {
+		t.Fatalf("expected %d entries, got %d", len(full), len(index))
+	}
+	for _, entry := range index {
+		tag, domains, err := DecodeEntry(data, entry)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", entry.Tag, err)
+		}
+		if tag != entry.Tag || !reflect.DeepEqual(domains, full[tag]) {
+			t.Errorf("%s: entry does not match full decode", entry.Tag)
+		}
+	}
+}
+
+func TestIndexBinary(t *testing.T) {
+	data := []byte("GEOS\x01")
+	data = append(data, 0x01, 'a', 0x01, 0x00, 0x03, 'a', '.', 'b')
+	data = append(data, 0x01, 'b', 0x01, 0x01, 0x03, 'c', '.', 'd')
+
+	index, err := Index(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(index) != 2 || index[1].Tag != "b" {
+		t.Fatalf("unexpected index: %+v", index)
+	}
+	tag, domains, err := DecodeEntry(data, index[1])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tag != "b" || len(domains) != 1 || domains[0] != "full:c.d" {
+		t.Errorf("unexpected entry: %s %v", tag, domains)
+	}
+}
+
+func TestIndexProtobufErrorMatchesReader(t *testing.T) {
+	data, err := os.ReadFile("../../example/zkeen-site.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, cut := range []int{1, 2, len(data) / 3, len(data) / 2, len(data) - 3} {
+		truncated := data[:len(data)-cut]
+		_, indexErr := Index(truncated)
+		_, decodeErr := DecodePartial(truncated)
+		var ie, de *geodata.DecodeError
+		if !errors.As(indexErr, &ie) || !errors.As(decodeErr, &de) {
+			t.Fatalf("cut %d: expected DecodeErrors, got %v and %v", cut, indexErr, decodeErr)
+		}
+		if !errors.Is(indexErr, ErrInvalidFormat) {
+			t.Errorf("cut %d: expected ErrInvalidFormat, got %v", cut, indexErr)
+		}
+		if ie.Offset != de.Offset || ie.Tag != de.Tag || ie.Index != de.Index {
+			t.Errorf("cut %d: index reports %v, reader reports %v", cut, indexErr, decodeErr)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+// internal/geosite/match.go
+package geosite
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Viktor45/dat2json/internal/geodata/router"
+)
+
+// Match is a geosite rule that matched a hostname.
+type Match struct {
+	Tag  string
+	Rule string
+}
+
+// ruleRef identifies a rule by its tag and position for stable output ordering.
+type ruleRef struct {
+	tag   string
+	index int
+	rule  string
+}
+
+type regexpRule struct {
+	ref ruleRef
+	re  *regexp.Regexp
+}
+
+type keywordRule struct {
+	ref   ruleRef
+	value string
+}
+
+// Matcher reports which geosite rules match a hostname using Xray semantics:
+// "domain:" matches the domain and its subdomains on a label boundary, "full:" matches
+// exactly, "keyword:" matches a substring and "regexp:" uses Go regular expressions.
+type Matcher struct {
+	full     map[string][]ruleRef
+	domain   map[string][]ruleRef
+	keywords []keywordRule
+	regexps  []regexpRule
+}
+
+// NewMatcher compiles decoded geosite data into a Matcher. Like Xray, it lower-cases
+// "full:", "domain:" and "keyword:" values; regular expressions are kept as they are.
+// It fails on rules that cannot be parsed or regular expressions that do not compile.
+func NewMatcher(data map[string][]string) (*Matcher, error) {
+	m := &Matcher{
+		full:   make(map[string][]ruleRef),
+		domain: make(map[string][]ruleRef),
+	}
+	for tag, rules := range data {
+		for i, s := range rules {
+			d, err := ParseRule(s)
+			if err != nil {
+				return nil, fmt.Errorf("tag %s: %w", tag, err)
+			}
+			ref := ruleRef{tag: tag, index: i, rule: s}
+			switch d.GetType() {
+			case router.Domain_Full:
+				value := strings.ToLower(d.GetValue())
+				m.full[value] = append(m.full[value], ref)
+			case router.Domain_Domain:
+				value := strings.ToLower(d.GetValue())
+				m.domain[value] = append(m.domain[value], ref)
+			case router.Domain_Plain:
+				m.keywords = append(m.keywords, keywordRule{ref: ref, value: strings.ToLower(d.GetValue())})
+			case router.Domain_Regex:
+				re, err := regexp.Compile(d.GetValue())
+				if err != nil {
+					return nil, fmt.Errorf("tag %s: rule %q: %w", tag, s, err)
+				}
+				m.regexps = append(m.regexps, regexpRule{ref: ref, re: re})
+			}
+		}
+	}
+	return m, nil
+}
+
+// Match returns every rule matching host, ordered by tag and rule position.
+// The host is lower-cased before matching, as Xray does.
+func (m *Matcher) Match(host string) []Match {
+	host = strings.ToLower(host)
+	var refs []ruleRef
+	refs = append(refs, m.full[host]...)
+	for suffix := host; ; {
+		refs = append(refs, m.domain[suffix]...)
+		i := strings.IndexByte(suffix, '.')
+		if i < 0 {
+			break
+		}
+		suffix = suffix[i+1:]
+	}
+	for _, k := range m.keywords {
+		if strings.Contains(host, k.value) {
+			refs = append(refs, k.ref)
+		}
+	}
+	for _, r := range m.regexps {
+		if r.re.MatchString(host) {
+			refs = append(refs, r.ref)
+		}
+	}
+
+	sort.Slice(refs, func(i, j int) bool {
+		if refs[i].tag != refs[j].tag {
+			return refs[i].tag < refs[j].tag
+		}
+		return refs[i].index < refs[j].index
+	})
+	matches := make([]Match, 0, len(refs))
+	for _, ref := range refs {
+		matches = append(matches, Match{Tag: ref.tag, Rule: ref.rule})
+	}
+	return matches
+}
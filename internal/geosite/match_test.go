@@ -0,0 +1,57 @@
+// internal/geosite/match_test.go
+package geosite
+
+import "testing"
+
+func TestMatcher(t *testing.T) {
+	m, err := NewMatcher(map[string][]string{
+		"google": {"domain:google.com", "full:mail.google.com", "keyword:goog", "regexp:^ma[a-z]+\\.", "domain:google.cn @cn"},
+		"other":  {"domain:com", "full:google.com"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		host     string
+		expected []string
+	}{
+		{"mail.google.com", []string{"google domain:google.com", "google full:mail.google.com", "google keyword:goog", "google regexp:^ma[a-z]+\\.", "other domain:com"}},
+		{"Google.COM", []string{"google domain:google.com", "google keyword:goog", "other domain:com", "other full:google.com"}},
+		{"notgoogle.cn", []string{"google keyword:goog"}},
+		{"www.google.cn", []string{"google keyword:goog", "google domain:google.cn @cn"}},
+		{"example.org", nil},
+	}
+	for _, tt := range tests {
+		matches := m.Match(tt.host)
+		if len(matches) != len(tt.expected) {
+			t.Errorf("%s: expected %v, got %v", tt.host, tt.expected, matches)
+			continue
+		}
+		for i, e := range tt.expected {
+			if got := matches[i].Tag + " " + matches[i].Rule; got != e {
+				t.Errorf("%s: match[%d]: expected %q, got %q", tt.host, i, e, got)
+			}
+		}
+	}
+}
+
+func TestMatcherUpperCaseRules(t *testing.T) {
+	m, err := NewMatcher(map[string][]string{
+		"x": {"full:Example.com", "domain:Example.ORG", "keyword:Google", "regexp:^A\\."},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for host, want := range map[string]int{"example.com": 1, "www.example.org": 1, "mygoogle.net": 1, "a.b": 0} {
+		if got := m.Match(host); len(got) != want {
+			t.Errorf("%s: expected %d matches, got %v", host, want, got)
+		}
+	}
+}
+
+func TestMatcherInvalidRegexp(t *testing.T) {
+	if _, err := NewMatcher(map[string][]string{"bad": {"regexp:("}}); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -0,0 +1,176 @@
+// internal/geosite/subsume.go
+package geosite
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Viktor45/dat2json/internal/geodata/router"
+)
+
+// Subsumed is a rule dropped because another rule matches every hostname it matches.
+type Subsumed struct {
+	Rule string
+	By   string
+}
+
+// parsedRule is a rule prepared for subsumption checks.
+type parsedRule struct {
+	raw   string
+	typ   router.Domain_Type
+	value string
+	attrs string // attributes in canonical order, compared for equality
+}
+
+type ruleKey struct {
+	typ   router.Domain_Type
+	value string
+}
+
+func (r parsedRule) key() ruleKey { return ruleKey{r.typ, r.value} }
+
+// exact reports whether the rule type can be reasoned about precisely:
+// "domain:" and "full:" rules describe a fixed set of hostnames.
+func (r parsedRule) exact() bool {
+	return r.typ == router.Domain_Domain || r.typ == router.Domain_Full
+}
+
+func parseRules(rules []string) ([]parsedRule, error) {
+	parsed := make([]parsedRule, 0, len(rules))
+	for _, s := range rules {
+		d, err := ParseRule(s)
+		if err != nil {
+			return nil, err
+		}
+		attrs := strings.Fields(formatAttributes(d.GetAttribute()))
+		sort.Strings(attrs)
+		parsed = append(parsed, parsedRule{
+			raw:   s,
+			typ:   d.GetType(),
+			value: d.GetValue(),
+			attrs: strings.Join(attrs, " "),
+		})
+	}
+	return parsed, nil
+}
+
+// ruleIndex finds the rules of a list that cover a given rule, i.e. match every
+// hostname the rule matches under Xray semantics.
+type ruleIndex struct {
+	rules    []parsedRule
+	exact    map[ruleKey][]int
+	domain   map[string][]int
+	keywords []int
+	regexps  []indexedRegexp
+}
+
+type indexedRegexp struct {
+	index int
+	re    *regexp.Regexp
+}
+
+func newRuleIndex(rules []parsedRule) (*ruleIndex, error) {
+	ix := &ruleIndex{
+		rules:  rules,
+		exact:  make(map[ruleKey][]int),
+		domain: make(map[string][]int),
+	}
+	for i, r := range rules {
+		ix.exact[r.key()] = append(ix.exact[r.key()], i)
+		switch r.typ {
+		case router.Domain_Domain:
+			ix.domain[r.value] = append(ix.domain[r.value], i)
+		case router.Domain_Plain:
+			ix.keywords = append(ix.keywords, i)
+		case router.Domain_Regex:
+			re, err := regexp.Compile(r.value)
+			if err != nil {
+				return nil, fmt.Errorf("rule %q: %w", r.raw, err)
+			}
+			ix.regexps = append(ix.regexps, indexedRegexp{index: i, re: re})
+		}
+	}
+	return ix, nil
+}
+
+// coveredBy reports whether some indexed rule accepted by accept covers r.
+// Identical rules always cover each other. A "domain:" rule covers itself, its
+// subdomains and the matching "full:" rules; a "keyword:" rule covers every rule
+// whose hostnames all contain it; a "regexp:" rule covers the "full:" names it matches.
+func (ix *ruleIndex) coveredBy(r parsedRule, accept func(j int) bool) bool {
+	for _, j := range ix.exact[r.key()] {
+		if accept(j) {
+			return true
+		}
+	}
+	if !r.exact() && r.typ != router.Domain_Plain {
+		return false
+	}
+	if r.exact() {
+		name := r.value
+		if r.typ == router.Domain_Full {
+			for _, j := range ix.domain[name] {
+				if accept(j) {
+					return true
+				}
+			}
+		}
+		for i := strings.IndexByte(name, '.'); i >= 0; i = strings.IndexByte(name, '.') {
+			name = name[i+1:]
+			for _, j := range ix.domain[name] {
+				if accept(j) {
+					return true
+				}
+			}
+		}
+	}
+	for _, j := range ix.keywords {
+		kw := ix.rules[j].value
+		if strings.Contains(r.value, kw) && accept(j) {
+			return true
+		}
+	}
+	if r.typ == router.Domain_Full {
+		for _, x := range ix.regexps {
+			if x.re.MatchString(r.value) && accept(x.index) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Prune removes rules that are duplicates of or subsumed by another rule in the list,
+// keeping the first of identical rules. A rule is only subsumed by one carrying the
+// same attributes, so attribute selectors such as "tag@cn" or "tag@!ads" still
+// select the same hostnames afterwards.
+func Prune(rules []string) (kept []string, dropped []Subsumed, err error) {
+	parsed, err := parseRules(rules)
+	if err != nil {
+		return nil, nil, err
+	}
+	ix, err := newRuleIndex(parsed)
+	if err != nil {
+		return nil, nil, err
+	}
+	kept = make([]string, 0, len(rules))
+	for i, r := range parsed {
+		by := -1
+		ix.coveredBy(r, func(j int) bool {
+			s := parsed[j]
+			if j == i || s.attrs != r.attrs || (s.key() == r.key() && j > i) {
+				return false
+			}
+			by = j
+			return true
+		})
+		if by >= 0 {
+			dropped = append(dropped, Subsumed{Rule: r.raw, By: parsed[by].raw})
+			continue
+		}
+		kept = append(kept, r.raw)
+	}
+	return kept, dropped, nil
+}
@@ -0,0 +1,62 @@
+// internal/geosite/subsume_test.go
+package geosite
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPrune(t *testing.T) {
+	kept, dropped, err := Prune([]string{
+		"full:mail.google.com",
+		"domain:google.com",
+		"domain:maps.google.com",
+		"full:google.com",
+		"domain:google.com",
+		"full:google.cn @cn",
+		"domain:google.cn",
+		"keyword:ads",
+		"domain:ads.example.com",
+		"full:tracker.io",
+		"regexp:^tracker\\.",
+		"regexp:^tracker\\.",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wantKept := []string{"domain:google.com", "full:google.cn @cn", "domain:google.cn", "keyword:ads", "regexp:^tracker\\."}
+	if !reflect.DeepEqual(kept, wantKept) {
+		t.Errorf("unexpected kept rules: %v", kept)
+	}
+	wantDropped := []Subsumed{
+		{"full:mail.google.com", "domain:google.com"},
+		{"domain:maps.google.com", "domain:google.com"},
+		{"full:google.com", "domain:google.com"},
+		{"domain:google.com", "domain:google.com"},
+		{"domain:ads.example.com", "keyword:ads"},
+		{"full:tracker.io", "regexp:^tracker\\."},
+		{"regexp:^tracker\\.", "regexp:^tracker\\."},
+	}
+	if !reflect.DeepEqual(dropped, wantDropped) {
+		t.Errorf("unexpected dropped rules: %v", dropped)
+	}
+}
+
+func TestPruneLabelBoundary(t *testing.T) {
+	kept, dropped, err := Prune([]string{"domain:google.com", "full:notgoogle.com", "domain:oogle.com"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(kept) != 3 || len(dropped) != 0 {
+		t.Errorf("unexpected result: kept %v, dropped %v", kept, dropped)
+	}
+}
+
+func TestPruneInvalid(t *testing.T) {
+	if _, _, err := Prune([]string{"regexp:("}); err == nil {
+		t.Error("expected error for invalid regexp")
+	}
+	if _, _, err := Prune([]string{"google.com"}); err == nil {
+		t.Error("expected error for missing prefix")
+	}
+}
@@ -0,0 +1,102 @@
+// internal/geosite/validate.go
+package geosite
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Viktor45/dat2json/internal/geodata"
+	"github.com/Viktor45/dat2json/internal/geodata/router"
+)
+
+// Validate checks decoded geosite data for rules routers reject or never match:
+// unparsable rules, regular expressions Go's regexp (used by Xray) cannot compile and
+// names with invalid characters or empty labels are errors; upper-case names and
+// keywords (which routers lower-case, but domain-list-community sources keep in lower
+// case), unknown rule types, duplicates, empty tags and unusual tag names are warnings.
+// Findings are ordered by tag and position.
+func Validate(data map[string][]string) []geodata.Finding {
+	var findings []geodata.Finding
+	for tag, rules := range data {
+		add := func(severity geodata.Severity, index int, value, format string, args ...any) {
+			findings = append(findings, geodata.Finding{
+				Severity: severity,
+				Tag:      tag,
+				Index:    index,
+				Value:    value,
+				Message:  fmt.Sprintf(format, args...),
+			})
+		}
+		if !geodata.ValidTag(tag) {
+			add(geodata.SeverityWarning, -1, "", "unusual characters in tag")
+		}
+		if len(rules) == 0 {
+			add(geodata.SeverityWarning, -1, "", "tag has no rules")
+		}
+		seen := make(map[ruleKey]int, len(rules))
+		for i, s := range rules {
+			d, err := ParseRule(s)
+			if err != nil {
+				add(geodata.SeverityError, i, s, "%v", err)
+				continue
+			}
+			value := d.GetValue()
+			switch d.GetType() {
+			case router.Domain_Domain, router.Domain_Full:
+				if err := checkDomainName(value); err != nil {
+					add(geodata.SeverityError, i, s, "%v", err)
+				} else if strings.ToLower(value) != value {
+					add(geodata.SeverityWarning, i, s, "upper-case name; domain-list-community expects lower case")
+				}
+			case router.Domain_Plain:
+				if value == "" {
+					add(geodata.SeverityError, i, s, "empty keyword")
+				} else if strings.ToLower(value) != value {
+					add(geodata.SeverityWarning, i, s, "upper-case keyword; domain-list-community expects lower case")
+				}
+			case router.Domain_Regex:
+				if _, err := regexp.Compile(value); err != nil {
+					add(geodata.SeverityError, i, s, "regexp does not compile: %v", err)
+				}
+			default:
+				add(geodata.SeverityWarning, i, s, "unknown rule type %d", d.GetType())
+			}
+			key := ruleKey{d.GetType(), value}
+			if j, ok := seen[key]; ok {
+				add(geodata.SeverityWarning, i, s, "duplicate of [%d]", j)
+				continue
+			}
+			seen[key] = i
+		}
+	}
+	geodata.SortFindings(findings)
+	return findings
+}
+
+// checkDomainName reports invalid characters, empty labels and lengths beyond the
+// DNS limits in a "domain:" or "full:" value. Upper-case letters are accepted here.
+func checkDomainName(name string) error {
+	if name == "" {
+		return fmt.Errorf("empty domain name")
+	}
+	if len(name) > 253 {
+		return fmt.Errorf("domain name longer than 253 characters")
+	}
+	for _, label := range strings.Split(name, ".") {
+		if label == "" {
+			return fmt.Errorf("empty label")
+		}
+		if len(label) > 63 {
+			return fmt.Errorf("label %q longer than 63 characters", label)
+		}
+		for _, c := range label {
+			switch {
+			case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_':
+			default:
+				return fmt.Errorf("invalid character %q", c)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+// internal/geosite/validate_test.go
+package geosite
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	findings := Validate(map[string][]string{
+		"google": {
+			"domain:google.com",
+			"full:Mail.Google.com",
+			"domain:google..com",
+			"full:bad_host!.com",
+			"regexp:(unclosed",
+			"keyword:",
+			"domain:google.com @cn",
+			"type9:opaque",
+			"google.com",
+			"domain:.google.com",
+		},
+		"empty":     {},
+		"google@cn": {"domain:google.cn"},
+	})
+	want := []string{
+		"warning: empty: tag has no rules",
+		"warning: google[1] full:Mail.Google.com: upper-case name; domain-list-community expects lower case",
+		`error: google[2] domain:google..com: empty label`,
+		`error: google[3] full:bad_host!.com: invalid character '!'`,
+		`error: google[4] regexp:(unclosed: regexp does not compile: error parsing regexp: missing closing ): ` + "`(unclosed`",
+		`error: google[5] keyword:: empty keyword`,
+		`warning: google[6] domain:google.com @cn: duplicate of [0]`,
+		`warning: google[7] type9:opaque: unknown rule type 9`,
+		`error: google[8] google.com: invalid rule "google.com": missing type prefix`,
+		`error: google[9] domain:.google.com: empty label`,
+		"warning: google@cn: unusual characters in tag",
+	}
+	if len(findings) != len(want) {
+		t.Fatalf("expected %d findings, got %d: %v", len(want), len(findings), findings)
+	}
+	for i, f := range findings {
+		if got := f.String(); got != want[i] {
+			t.Errorf("finding %d = %q, want %q", i, got, want[i])
+		}
+	}
+}
+
+func TestCheckDomainName(t *testing.T) {
+	for _, name := range []string{"example.com", "cn", "xn--80ak6aa92e.com", "_dmarc.example.com"} {
+		if err := checkDomainName(name); err != nil {
+			t.Errorf("checkDomainName(%q): unexpected error: %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "a..b", "example.com.", "пример.рф", "a b.com", strings.Repeat("a", 64) + ".com"} {
+		if err := checkDomainName(name); err == nil {
+			t.Errorf("checkDomainName(%q): expected error", name)
+		}
+	}
+}
@@ -0,0 +1,306 @@
+// internal/ruleset/binary.go
+package ruleset
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/netip"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/Viktor45/dat2json/pkg/format"
+
+	"go4.org/netipx"
+)
+
+// Rule and item types of the binary layout, as numbered by sing-box.
+const (
+	ruleDefault       = 0
+	itemDomain        = 2
+	itemDomainKeyword = 3
+	itemDomainRegex   = 4
+	itemIPCIDR        = 6
+	itemFinal         = 0xFF
+)
+
+// Labels that terminate a reversed domain in the succinct set.
+const (
+	prefixLabel = '\r'
+	rootLabel   = '\n'
+)
+
+// maxCount bounds decoded counts so that corrupted data cannot request huge allocations.
+const maxCount = 1 << 24
+
+// DecodeBinary decodes a binary (.srs) rule-set. Only default rules with domain,
+// domain_suffix, domain_keyword, domain_regex and ip_cidr items are supported;
+// other items have no geodata equivalent and are reported as errors.
+func DecodeBinary(data []byte) (*format.SingBoxRuleSet, error) {
+	if !IsBinary(data) || len(data) < len(format.SRSMagic)+1 {
+		return nil, ErrInvalidFormat
+	}
+	version := int(data[len(format.SRSMagic)])
+	if version < 1 || version > maxVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidFormat, version)
+	}
+	zr, err := zlib.NewReader(bytes.NewReader(data[len(format.SRSMagic)+1:]))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFormat, err)
+	}
+	r := bufio.NewReader(zr)
+	count, err := readCount(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: read rule count: %v", ErrInvalidFormat, err)
+	}
+	rs := &format.SingBoxRuleSet{Version: version, Rules: make([]format.SingBoxRule, 0, count)}
+	for i := 0; i < count; i++ {
+		rule, err := readRule(r)
+		if err != nil {
+			return nil, fmt.Errorf("%w: rule %d: %v", ErrInvalidFormat, i, err)
+		}
+		rs.Rules = append(rs.Rules, rule)
+	}
+	return rs, nil
+}
+
+func readRule(r *bufio.Reader) (format.SingBoxRule, error) {
+	var rule format.SingBoxRule
+	typ, err := r.ReadByte()
+	if err != nil {
+		return rule, err
+	}
+	if typ != ruleDefault {
+		return rule, fmt.Errorf("unsupported rule type %d (only default rules are supported)", typ)
+	}
+	for {
+		item, err := r.ReadByte()
+		if err != nil {
+			return rule, err
+		}
+		switch item {
+		case itemDomain:
+			rule.Domain, rule.DomainSuffix, err = readDomains(r)
+		case itemDomainKeyword:
+			rule.DomainKeyword, err = readStrings(r)
+		case itemDomainRegex:
+			rule.DomainRegex, err = readStrings(r)
+		case itemIPCIDR:
+			rule.IPCIDR, err = readIPSet(r)
+		case itemFinal:
+			invert, err := r.ReadByte()
+			rule.Invert = invert != 0
+			return rule, err
+		default:
+			return rule, fmt.Errorf("unsupported rule item %d", item)
+		}
+		if err != nil {
+			return rule, fmt.Errorf("item %d: %w", item, err)
+		}
+	}
+}
+
+// readDomains reads the succinct set of a domain item and splits its keys into
+// domains and suffixes. Suffixes stored the version 1 way, as "x" plus ".x", are
+// merged back into "x".
+func readDomains(r *bufio.Reader) (domains, suffixes []string, err error) {
+	if _, err := r.ReadByte(); err != nil { // reserved
+		return nil, nil, err
+	}
+	leaves, err := readWords(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	labelBitmap, err := readWords(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	labels, err := readBytes(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	keys, err := succinctKeys(leaves, labelBitmap, labels)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	exact := make(map[string]bool)
+	var dotted []string
+	for _, key := range keys {
+		key = reverseDomain(key)
+		switch {
+		case key == "":
+			return nil, nil, fmt.Errorf("empty domain")
+		case key[0] == prefixLabel:
+			dotted = append(dotted, key[1:])
+		case key[0] == rootLabel:
+			suffixes = append(suffixes, key[1:])
+		default:
+			exact[key] = true
+		}
+	}
+	for _, d := range dotted {
+		if len(d) > 1 && d[0] == '.' && exact[d[1:]] {
+			root := d[1:]
+			delete(exact, root)
+			suffixes = append(suffixes, root)
+			continue
+		}
+		suffixes = append(suffixes, d)
+	}
+	for d := range exact {
+		domains = append(domains, d)
+	}
+	sort.Strings(domains)
+	sort.Strings(suffixes)
+	return domains, suffixes, nil
+}
+
+// succinctKeys lists the keys of a LOUDS-encoded trie. Nodes are numbered in
+// breadth-first order, so each 0 bit in labelBitmap is an edge from the current
+// node to the next unnumbered node and each 1 bit closes the current node.
+func succinctKeys(leaves, labelBitmap []uint64, labels []byte) ([]string, error) {
+	paths := []string{""}
+	var keys []string
+	node, edge := 0, 0
+	for bit := 0; node < len(paths); bit++ {
+		if bit>>6 >= len(labelBitmap) {
+			return nil, fmt.Errorf("succinct set: label bitmap ends inside node %d", node)
+		}
+		if labelBitmap[bit>>6]&(1<<uint(bit&63)) == 0 {
+			if edge >= len(labels) {
+				return nil, fmt.Errorf("succinct set: more edges than labels")
+			}
+			paths = append(paths, paths[node]+string(labels[edge:edge+1]))
+			edge++
+			continue
+		}
+		if node>>6 < len(leaves) && leaves[node>>6]&(1<<uint(node&63)) != 0 {
+			keys = append(keys, paths[node])
+		}
+		node++
+	}
+	return keys, nil
+}
+
+// reverseDomain undoes the rune-by-rune reversal applied to stored keys.
+func reverseDomain(domain string) string {
+	n := len(domain)
+	b := make([]byte, n)
+	for i := 0; i < n; {
+		r, size := utf8.DecodeRuneInString(domain[i:])
+		i += size
+		utf8.EncodeRune(b[n-i:], r)
+	}
+	return string(b)
+}
+
+// readIPSet reads the address ranges of an IP set and returns them as CIDRs.
+func readIPSet(r *bufio.Reader) ([]string, error) {
+	version, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	if version != 1 {
+		return nil, fmt.Errorf("unsupported IP set version %d", version)
+	}
+	var count uint64
+	if err := binary.Read(r, binary.BigEndian, &count); err != nil {
+		return nil, err
+	}
+	if count > maxCount {
+		return nil, fmt.Errorf("too many ranges: %d", count)
+	}
+	var b netipx.IPSetBuilder
+	for i := uint64(0); i < count; i++ {
+		from, err := readAddr(r)
+		if err != nil {
+			return nil, fmt.Errorf("range %d: %w", i, err)
+		}
+		to, err := readAddr(r)
+		if err != nil {
+			return nil, fmt.Errorf("range %d: %w", i, err)
+		}
+		ipRange := netipx.IPRangeFrom(from, to)
+		if !ipRange.IsValid() {
+			return nil, fmt.Errorf("range %d: invalid range %s-%s", i, from, to)
+		}
+		b.AddRange(ipRange)
+	}
+	set, err := b.IPSet()
+	if err != nil {
+		return nil, err
+	}
+	prefixes := set.Prefixes()
+	cidrs := make([]string, len(prefixes))
+	for i, p := range prefixes {
+		cidrs[i] = p.String()
+	}
+	return cidrs, nil
+}
+
+func readAddr(r *bufio.Reader) (netip.Addr, error) {
+	raw, err := readBytes(r)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	addr, ok := netip.AddrFromSlice(raw)
+	if !ok {
+		return netip.Addr{}, fmt.Errorf("invalid address length %d", len(raw))
+	}
+	return addr, nil
+}
+
+func readCount(r *bufio.Reader) (int, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return 0, err
+	}
+	if n > maxCount {
+		return 0, fmt.Errorf("count too large: %d", n)
+	}
+	return int(n), nil
+}
+
+func readWords(r *bufio.Reader) ([]uint64, error) {
+	n, err := readCount(r)
+	if err != nil {
+		return nil, err
+	}
+	words := make([]uint64, n)
+	if err := binary.Read(r, binary.BigEndian, words); err != nil {
+		return nil, err
+	}
+	return words, nil
+}
+
+func readBytes(r *bufio.Reader) ([]byte, error) {
+	n, err := readCount(r)
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func readStrings(r *bufio.Reader) ([]string, error) {
+	n, err := readCount(r)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]string, 0, min(n, 1024))
+	for i := 0; i < n; i++ {
+		b, err := readBytes(r)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, string(b))
+	}
+	return values, nil
+}
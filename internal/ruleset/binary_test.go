@@ -0,0 +1,91 @@
+// internal/ruleset/binary_test.go
+package ruleset
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/Viktor45/dat2json/pkg/format"
+)
+
+// srsFile wraps an uncompressed body in the binary rule-set header.
+func srsFile(t *testing.T, version byte, body []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	buf.WriteString("SRS")
+	buf.WriteByte(version)
+	zw := zlib.NewWriter(&buf)
+	if _, err := zw.Write(body); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecodeBinaryFixture(t *testing.T) {
+	// One rule: suffix "a" (key "a\n"), keyword "b", regexp "^c$", then 1.2.3.0/24.
+	body := []byte{
+		0x01, 0x00,
+		0x02, 0x00,
+		0x01, 0, 0, 0, 0, 0, 0, 0, 0x04,
+		0x01, 0, 0, 0, 0, 0, 0, 0, 0x1a,
+		0x02, 'a', '\n',
+		0x03, 0x01, 0x01, 'b',
+		0x04, 0x01, 0x03, '^', 'c', '$',
+		0x06, 0x01, 0, 0, 0, 0, 0, 0, 0, 0x01, 0x04, 1, 2, 3, 0, 0x04, 1, 2, 3, 255,
+		0xff, 0x00,
+	}
+	rs, err := DecodeBinary(srsFile(t, 2, body))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := &format.SingBoxRuleSet{Version: 2, Rules: []format.SingBoxRule{{
+		DomainSuffix:  []string{"a"},
+		DomainKeyword: []string{"b"},
+		DomainRegex:   []string{"^c$"},
+		IPCIDR:        []string{"1.2.3.0/24"},
+	}}}
+	if !reflect.DeepEqual(rs, want) {
+		t.Errorf("unexpected rule-set: %+v", rs)
+	}
+}
+
+func TestDecodeBinaryRoundTrip(t *testing.T) {
+	for _, version := range []int{1, 2} {
+		in := &format.SingBoxRuleSet{Version: version, Rules: []format.SingBoxRule{
+			{Domain: []string{"example.com", "www.example.org"}, DomainSuffix: []string{".cdn.example", "example.org", "пример.рф"}},
+			{IPCIDR: []string{"10.0.0.0/8", "2001:db8::/32"}, Invert: true},
+		}}
+		data, err := format.EncodeSRS(in)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		out, err := DecodeBinary(data)
+		if err != nil {
+			t.Fatalf("version %d: unexpected error: %v", version, err)
+		}
+		if !reflect.DeepEqual(out, in) {
+			t.Errorf("version %d: got %+v, want %+v", version, out, in)
+		}
+	}
+}
+
+func TestDecodeBinaryInvalid(t *testing.T) {
+	tests := map[string][]byte{
+		"no magic":         []byte("XYZ\x02"),
+		"future version":   srsFile(t, 9, []byte{0x00}),
+		"logical rule":     srsFile(t, 2, []byte{0x01, 0x01}),
+		"unsupported item": srsFile(t, 2, []byte{0x01, 0x00, 0x09, 0x00}),
+		"truncated":        srsFile(t, 2, []byte{0x01, 0x00, 0x03, 0x02, 0x01}),
+	}
+	for name, data := range tests {
+		if _, err := DecodeBinary(data); !errors.Is(err, ErrInvalidFormat) {
+			t.Errorf("%s: expected ErrInvalidFormat, got %v", name, err)
+		}
+	}
+}
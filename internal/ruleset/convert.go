@@ -0,0 +1,83 @@
+// internal/ruleset/convert.go
+package ruleset
+
+import (
+	"fmt"
+	"net/netip"
+	"regexp"
+	"strings"
+
+	"github.com/Viktor45/dat2json/internal/geoip"
+	"github.com/Viktor45/dat2json/pkg/format"
+)
+
+// Entries converts a decoded rule-set into a single geosite tag or geoip entry named tag,
+// the reverse of format.SingBoxRules. For geosite, domain items become "full:" rules,
+// domain_suffix items "domain:" rules, domain_keyword items "keyword:" rules and
+// domain_regex items "regexp:" rules; a suffix with a leading dot, which matches
+// subdomains only, becomes an equivalent "regexp:" rule. For geoip, ip_cidr items
+// become CIDRs, with bare addresses written as /32 or /128 prefixes, and a rule-set
+// made of a single inverted rule becomes the reverse-match entry "!TAG".
+//
+// Items of the other kind (CIDRs when decoding geosite and vice versa) are left out;
+// it is an error if the rule-set has nothing but such items.
+func Entries(rs *format.SingBoxRuleSet, tag string, isGeoSite bool) (map[string][]string, error) {
+	var values []string
+	skipped := 0
+	for i, rule := range rs.Rules {
+		if rule.Invert && (isGeoSite || len(rs.Rules) > 1) {
+			return nil, fmt.Errorf("rule %d: inverted rules can only be converted as the single rule of a geoip rule-set", i)
+		}
+		domains := len(rule.Domain) + len(rule.DomainSuffix) + len(rule.DomainKeyword) + len(rule.DomainRegex)
+		if !isGeoSite {
+			for _, c := range rule.IPCIDR {
+				values = append(values, hostPrefix(c))
+			}
+			skipped += domains
+			continue
+		}
+		skipped += len(rule.IPCIDR)
+		for _, d := range rule.Domain {
+			values = append(values, "full:"+d)
+		}
+		for _, d := range rule.DomainSuffix {
+			if strings.HasPrefix(d, ".") {
+				values = append(values, "regexp:"+regexp.QuoteMeta(d)+"$")
+			} else {
+				values = append(values, "domain:"+d)
+			}
+		}
+		for _, k := range rule.DomainKeyword {
+			values = append(values, "keyword:"+k)
+		}
+		for _, r := range rule.DomainRegex {
+			values = append(values, "regexp:"+r)
+		}
+	}
+	if len(values) == 0 && skipped > 0 {
+		if isGeoSite {
+			return nil, fmt.Errorf("rule-set has only IP CIDR rules (decode it as geoip)")
+		}
+		return nil, fmt.Errorf("rule-set has only domain rules (decode it as geosite)")
+	}
+	if values == nil {
+		values = []string{}
+	}
+	if len(rs.Rules) == 1 && rs.Rules[0].Invert {
+		tag = geoip.ReverseMatchPrefix + tag
+	}
+	return map[string][]string{tag: values}, nil
+}
+
+// hostPrefix returns a bare IP address of an ip_cidr item as a single-host prefix.
+// Other items are returned unchanged.
+func hostPrefix(c string) string {
+	if strings.Contains(c, "/") {
+		return c
+	}
+	addr, err := netip.ParseAddr(c)
+	if err != nil {
+		return c
+	}
+	return netip.PrefixFrom(addr.WithZone(""), addr.BitLen()).String()
+}
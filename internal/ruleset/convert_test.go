@@ -0,0 +1,62 @@
+// internal/ruleset/convert_test.go
+package ruleset
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Viktor45/dat2json/pkg/format"
+)
+
+func TestEntriesGeoSite(t *testing.T) {
+	rs := &format.SingBoxRuleSet{Version: 2, Rules: []format.SingBoxRule{
+		{Domain: []string{"www.example.com"}, DomainSuffix: []string{"example.org", ".cdn.example"}, IPCIDR: []string{"10.0.0.0/8"}},
+		{DomainKeyword: []string{"ads"}, DomainRegex: []string{`^x\d+$`}},
+	}}
+	got, err := Entries(rs, "custom", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string][]string{"custom": {
+		"full:www.example.com", "domain:example.org", `regexp:\.cdn\.example$`, "keyword:ads", `regexp:^x\d+$`,
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected entries: %v", got)
+	}
+}
+
+func TestEntriesGeoIP(t *testing.T) {
+	rs := &format.SingBoxRuleSet{Version: 2, Rules: []format.SingBoxRule{{IPCIDR: []string{"1.0.1.0/24"}, Invert: true}}}
+	got, err := Entries(rs, "CN", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, map[string][]string{"!CN": {"1.0.1.0/24"}}) {
+		t.Errorf("unexpected entries: %v", got)
+	}
+}
+
+func TestEntriesGeoIPBareAddresses(t *testing.T) {
+	rs := &format.SingBoxRuleSet{Version: 2, Rules: []format.SingBoxRule{
+		{IPCIDR: []string{"1.1.1.1", "2001:db8::1", "10.0.0.0/8"}},
+	}}
+	got, err := Entries(rs, "US", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string][]string{"US": {"1.1.1.1/32", "2001:db8::1/128", "10.0.0.0/8"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected entries: %v", got)
+	}
+}
+
+func TestEntriesErrors(t *testing.T) {
+	domains := &format.SingBoxRuleSet{Version: 2, Rules: []format.SingBoxRule{{Domain: []string{"a.com"}}}}
+	if _, err := Entries(domains, "US", false); err == nil {
+		t.Error("expected error for domain-only rule-set decoded as geoip")
+	}
+	inverted := &format.SingBoxRuleSet{Version: 2, Rules: []format.SingBoxRule{{Domain: []string{"a.com"}, Invert: true}}}
+	if _, err := Entries(inverted, "x", true); err == nil {
+		t.Error("expected error for inverted geosite rule")
+	}
+}
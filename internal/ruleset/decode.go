@@ -0,0 +1,44 @@
+// Package ruleset provides decoding functionality for sing-box rule-sets in binary (.srs) or source (JSON) format.
+package ruleset
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Viktor45/dat2json/pkg/format"
+)
+
+// ErrInvalidFormat is returned when the data does not represent a valid sing-box rule-set.
+var ErrInvalidFormat = fmt.Errorf("not a valid sing-box rule-set")
+
+// maxVersion is the newest rule-set version the decoder understands.
+const maxVersion = 3
+
+// Decode decodes a binary or source rule-set, telling them apart by the "SRS" magic.
+func Decode(data []byte) (*format.SingBoxRuleSet, error) {
+	if IsBinary(data) {
+		return DecodeBinary(data)
+	}
+	return DecodeSource(data)
+}
+
+// IsBinary reports whether data starts with the binary rule-set magic.
+func IsBinary(data []byte) bool {
+	return bytes.HasPrefix(data, format.SRSMagic[:])
+}
+
+// IsSource reports whether data is a JSON object with a numeric "version" and a "rules"
+// list, as opposed to a JSON export of geodata entries.
+func IsSource(data []byte) bool {
+	var top map[string]json.RawMessage
+	if err := json.Unmarshal(data, &top); err != nil {
+		return false
+	}
+	var version int
+	if err := json.Unmarshal(top["version"], &version); err != nil {
+		return false
+	}
+	rules, ok := top["rules"]
+	return ok && bytes.HasPrefix(bytes.TrimSpace(rules), []byte("["))
+}
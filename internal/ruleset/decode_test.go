@@ -0,0 +1,40 @@
+// internal/ruleset/decode_test.go
+package ruleset
+
+import (
+	"testing"
+
+	"github.com/Viktor45/dat2json/pkg/format"
+)
+
+func TestDecodeDetectsFormat(t *testing.T) {
+	in := &format.SingBoxRuleSet{Version: 2, Rules: []format.SingBoxRule{{DomainKeyword: []string{"ads"}}}}
+	binary, err := format.EncodeSRS(in)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, data := range [][]byte{binary, []byte(`{"version": 2, "rules": [{"domain_keyword": "ads"}]}`)} {
+		rs, err := Decode(data)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(rs.Rules) != 1 || rs.Rules[0].DomainKeyword[0] != "ads" {
+			t.Errorf("unexpected rule-set: %+v", rs)
+		}
+	}
+}
+
+func TestIsSource(t *testing.T) {
+	tests := map[string]bool{
+		`{"version": 2, "rules": []}`:                                true,
+		`{"rules": ["domain:a.com"]}`:                                false,
+		`{"version": ["full:v.example"], "rules": ["domain:a.com"]}`: false,
+		`{"version": 1, "rules": "not a list"}`:                      false,
+		`not json`:                                                   false,
+	}
+	for data, want := range tests {
+		if got := IsSource([]byte(data)); got != want {
+			t.Errorf("IsSource(%s) = %v, want %v", data, got, want)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+// internal/ruleset/source.go
+package ruleset
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Viktor45/dat2json/pkg/format"
+)
+
+// listable is a JSON value written either as a single string or as a list of strings.
+type listable []string
+
+func (l *listable) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*l = listable{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// DecodeSource decodes a source (JSON) rule-set. As with DecodeBinary, only default
+// rules with domain, domain_suffix, domain_keyword, domain_regex and ip_cidr fields
+// are supported; other fields are reported as errors rather than silently dropped.
+func DecodeSource(data []byte) (*format.SingBoxRuleSet, error) {
+	var raw struct {
+		Version int               `json:"version"`
+		Rules   []json.RawMessage `json:"rules"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFormat, err)
+	}
+	if raw.Version < 1 || raw.Version > maxVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidFormat, raw.Version)
+	}
+	rs := &format.SingBoxRuleSet{Version: raw.Version, Rules: make([]format.SingBoxRule, 0, len(raw.Rules))}
+	for i, msg := range raw.Rules {
+		rule, err := decodeSourceRule(msg)
+		if err != nil {
+			return nil, fmt.Errorf("%w: rule %d: %v", ErrInvalidFormat, i, err)
+		}
+		rs.Rules = append(rs.Rules, rule)
+	}
+	return rs, nil
+}
+
+func decodeSourceRule(msg json.RawMessage) (format.SingBoxRule, error) {
+	var rule format.SingBoxRule
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(msg, &fields); err != nil {
+		return rule, err
+	}
+	var unsupported []string
+	for name, value := range fields {
+		var target *[]string
+		switch name {
+		case "type":
+			var typ string
+			if err := json.Unmarshal(value, &typ); err != nil {
+				return rule, fmt.Errorf("type: %w", err)
+			}
+			if typ != "" && typ != "default" {
+				return rule, fmt.Errorf("unsupported rule type %q (only default rules are supported)", typ)
+			}
+			continue
+		case "invert":
+			if err := json.Unmarshal(value, &rule.Invert); err != nil {
+				return rule, fmt.Errorf("invert: %w", err)
+			}
+			continue
+		case "domain":
+			target = &rule.Domain
+		case "domain_suffix":
+			target = &rule.DomainSuffix
+		case "domain_keyword":
+			target = &rule.DomainKeyword
+		case "domain_regex":
+			target = &rule.DomainRegex
+		case "ip_cidr":
+			target = &rule.IPCIDR
+		default:
+			unsupported = append(unsupported, name)
+			continue
+		}
+		var l listable
+		if err := json.Unmarshal(value, &l); err != nil {
+			return rule, fmt.Errorf("%s: %w", name, err)
+		}
+		*target = l
+	}
+	if len(unsupported) > 0 {
+		sort.Strings(unsupported)
+		return rule, fmt.Errorf("unsupported fields: %s", strings.Join(unsupported, ", "))
+	}
+	return rule, nil
+}
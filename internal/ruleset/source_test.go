@@ -0,0 +1,45 @@
+// internal/ruleset/source_test.go
+package ruleset
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/Viktor45/dat2json/pkg/format"
+)
+
+func TestDecodeSource(t *testing.T) {
+	data := []byte(`{
+  "version": 3,
+  "rules": [
+    {"domain": "example.com", "domain_suffix": [".example.org"], "domain_keyword": ["ads"]},
+    {"type": "default", "ip_cidr": ["10.0.0.0/8"], "invert": true}
+  ]
+}`)
+	rs, err := DecodeSource(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := &format.SingBoxRuleSet{Version: 3, Rules: []format.SingBoxRule{
+		{Domain: []string{"example.com"}, DomainSuffix: []string{".example.org"}, DomainKeyword: []string{"ads"}},
+		{IPCIDR: []string{"10.0.0.0/8"}, Invert: true},
+	}}
+	if !reflect.DeepEqual(rs, want) {
+		t.Errorf("unexpected rule-set: %+v", rs)
+	}
+}
+
+func TestDecodeSourceInvalid(t *testing.T) {
+	tests := map[string]string{
+		"no version":         `{"rules": []}`,
+		"logical rule":       `{"version": 2, "rules": [{"type": "logical", "mode": "and", "rules": []}]}`,
+		"unsupported fields": `{"version": 2, "rules": [{"domain": ["a.com"], "port": [443], "process_name": ["x"]}]}`,
+		"bad list":           `{"version": 2, "rules": [{"domain": [1]}]}`,
+	}
+	for name, data := range tests {
+		if _, err := DecodeSource([]byte(data)); !errors.Is(err, ErrInvalidFormat) {
+			t.Errorf("%s: expected ErrInvalidFormat, got %v", name, err)
+		}
+	}
+}
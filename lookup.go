@@ -0,0 +1,85 @@
+// lookup.go
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"strings"
+
+	"github.com/Viktor45/dat2json/internal/geoip"
+)
+
+// runLookup implements "dat2json lookup": it reports which geoip countries contain
+// each address given on the command line, or read from stdin when none are given.
+func runLookup(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("lookup", flag.ContinueOnError)
+	input := fs.String("i", "", "Input geoip.dat file (or .json/.yaml/.yml source)")
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(fs.Output(), "Usage: %s lookup -i geoip.dat [IP...]\n", os.Args[0])
+		_, _ = fmt.Fprintln(fs.Output(), "\nReads addresses from stdin when none are given.")
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *input == "" {
+		return fmt.Errorf("-i input file is required")
+	}
+
+	decoded, err := loadInput(*input, false)
+	if err != nil {
+		return err
+	}
+	table, err := geoip.NewTable(decoded)
+	if err != nil {
+		return fmt.Errorf("error building lookup table: %w", err)
+	}
+
+	lookup := func(s string) error {
+		addr, err := netip.ParseAddr(s)
+		if err != nil {
+			_, _ = fmt.Fprintf(fs.Output(), "⚠️ Warning: invalid IP address '%s'\n", s)
+			return nil
+		}
+		_, err = fmt.Fprintln(stdout, formatMatches(addr, table.Lookup(addr)))
+		return err
+	}
+
+	if fs.NArg() > 0 {
+		for _, arg := range fs.Args() {
+			if err := lookup(arg); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	scanner := bufio.NewScanner(stdin)
+	for scanner.Scan() {
+		for _, field := range strings.Fields(scanner.Text()) {
+			if err := lookup(field); err != nil {
+				return err
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// formatMatches renders a lookup result as "ADDR: CC (CIDR), ...", most specific first,
+// with reverse-match entries as "!CC (outside list)".
+func formatMatches(addr netip.Addr, matches []geoip.Match) string {
+	if len(matches) == 0 {
+		return addr.String() + ": no match"
+	}
+	parts := make([]string, 0, len(matches))
+	for _, m := range matches {
+		if !m.Prefix.IsValid() {
+			parts = append(parts, m.Country+" (outside list)")
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s (%s)", m.Country, m.Prefix))
+	}
+	return addr.String() + ": " + strings.Join(parts, ", ")
+}
@@ -0,0 +1,61 @@
+// lookup_test.go
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunLookup(t *testing.T) {
+	inputFile := filepath.Join(t.TempDir(), "geoip.json")
+	source := `{"US": ["1.2.0.0/16"], "EXAMPLE": ["1.2.3.0/24"]}`
+	if err := os.WriteFile(inputFile, []byte(source), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := runLookup([]string{"-i", inputFile, "1.2.3.4", "9.9.9.9"}, nil, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "1.2.3.4: EXAMPLE (1.2.3.0/24), US (1.2.0.0/16)\n9.9.9.9: no match\n"
+	if out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+}
+
+func TestRunLookupReverseMatch(t *testing.T) {
+	inputFile := filepath.Join(t.TempDir(), "geoip.json")
+	source := `{"!CN": ["1.0.1.0/24"]}`
+	if err := os.WriteFile(inputFile, []byte(source), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := runLookup([]string{"-i", inputFile, "1.0.1.1", "9.9.9.9"}, nil, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "1.0.1.1: no match\n9.9.9.9: !CN (outside list)\n"
+	if out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+}
+
+func TestRunLookupStdin(t *testing.T) {
+	var out bytes.Buffer
+	stdin := strings.NewReader("149.154.167.99\n")
+	if err := runLookup([]string{"-i", "example/zkeen-ip.dat"}, stdin, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "TELEGRAM") {
+		t.Errorf("unexpected output: %q", out.String())
+	}
+}
+
+func TestRunLookupMissingInput(t *testing.T) {
+	if err := runLookup(nil, nil, &bytes.Buffer{}); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -2,8 +2,11 @@
 package main
 
 import (
+	"bytes"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
@@ -11,33 +14,60 @@ import (
 	"strings"
 	"sync"
 
-	"dat2json/internal/geoip"
-	"dat2json/internal/geosite"
-	"dat2json/pkg/format"
+	"github.com/Viktor45/dat2json/internal/geodata"
+	"github.com/Viktor45/dat2json/internal/geoip"
+	"github.com/Viktor45/dat2json/internal/geosite"
+	"github.com/Viktor45/dat2json/internal/ruleset"
+	"github.com/Viktor45/dat2json/pkg/format"
 )
 
 var (
-	inputFile     = flag.String("i", "", "Input .dat file")
-	outputFile    = flag.String("o", "", "Output file (.json/.yaml/.yml)")
+	inputFiles    = newStringList("i", "Input .dat file (or .json/.yaml/.yml source, or sing-box .srs/.json rule-set); repeat to merge inputs")
+	outputFile    = flag.String("o", "", "Output file (.json/.yaml/.yml/.dat/.srs/.mrs)")
 	outputDir     = flag.String("output-dir", "", "Output directory for per-tag/country files")
-	tagFilter     = flag.String("tag", "", "Comma-separated tags (geosite only)")
+	tagFilter     = flag.String("tag", "", "Comma-separated tags, optionally with @attr/@!attr selectors (geosite only)")
 	countryFilter = flag.String("country", "", "Comma-separated country codes (geoip only)")
-	listTags      = flag.Bool("list-tags", false, "List all tags in geosite.dat and exit")
+	listTags      = flag.Bool("list-tags", false, "List all tags/countries in the input and exit")
 	sortKeys      = flag.Bool("sort", false, "Sort keys")
-	formatFlag    = flag.String("format", "", "Output format: json or yaml")
+	aggregate     = flag.Bool("aggregate", false, "Merge overlapping and adjacent CIDRs (geoip only)")
+	canonical     = flag.Bool("canonical", false, "Mask host bits in CIDRs, e.g. 1.2.3.4/24 becomes 1.2.3.0/24 (geoip only)")
+	familyFlag    = flag.String("family", geoip.FamilyBoth, "Address family to keep: ipv4, ipv6 or both (geoip only)")
+	splitFamily   = flag.Bool("split-family", false, "With --output-dir, write IPv4 and IPv6 CIDRs to separate {name}.v4/{name}.v6 files (geoip only)")
+	exprFlag      = flag.String("expr", "", "Set expression over countries or tags, e.g. \"CN - PRIVATE\" or \"!(RU | BY)\"")
+	exprTag       = flag.String("expr-tag", "expr", "Name of the entry produced by --expr")
+	pruneFlag     = flag.Bool("prune", false, "Drop rules duplicated or subsumed by another rule in the same tag (geosite only)")
+	partialFlag   = flag.Bool("partial", false, "Keep the entries decoded before the first corrupted byte of a .dat input instead of failing")
+	mergeFlag     = flag.String("merge", mergeUnion, "Conflict policy for repeated -i: union, prefer-first, prefer-last or error, optionally per tag (union,google=prefer-last)")
+	formatFlag    = flag.String("format", "", "Output format: json, yaml, dat, singbox, srs, mihomo-yaml, mihomo-text, mrs or dlc")
 	ipMode        = flag.Bool("ip", false, "Treat input as geoip.dat")
 	siteMode      = flag.Bool("site", false, "Treat input as geosite.dat")
 	help          = flag.Bool("h", false, "Show help")
 )
 
+// newStringList defines a repeatable string flag on the command line.
+func newStringList(name, usage string) *stringList {
+	l := &stringList{}
+	flag.Var(l, name, usage)
+	return l
+}
+
 func isValidFormat(f string) bool {
-	return f == "json" || f == "yaml"
+	return f == "json" || f == "yaml" || f == "dat" || f == "singbox" || f == "srs" || isMihomoFormat(f) || f == "dlc"
+}
+
+// isMihomoFormat reports whether f is one of the Mihomo rule-provider formats.
+func isMihomoFormat(f string) bool {
+	return f == "mihomo-yaml" || f == "mihomo-text" || f == "mrs"
+}
+
+func isValidFamily(f string) bool {
+	return f == geoip.FamilyIPv4 || f == geoip.FamilyIPv6 || f == geoip.FamilyBoth
 }
 
 func getOutputFormat() (string, error) {
 	if *formatFlag != "" {
 		if !isValidFormat(*formatFlag) {
-			return "", fmt.Errorf("--format must be 'json' or 'yaml'")
+			return "", fmt.Errorf("--format must be 'json', 'yaml', 'dat', 'singbox', 'srs', 'mihomo-yaml', 'mihomo-text', 'mrs' or 'dlc'")
 		}
 		return *formatFlag, nil
 	}
@@ -49,6 +79,12 @@ func getOutputFormat() (string, error) {
 			return "json", nil
 		case ".yaml", ".yml":
 			return "yaml", nil
+		case ".dat":
+			return "dat", nil
+		case ".srs":
+			return "srs", nil
+		case ".mrs":
+			return "mrs", nil
 		default:
 			return "", fmt.Errorf("cannot determine format from extension %q", ext)
 		}
@@ -61,6 +97,234 @@ func getOutputFormat() (string, error) {
 	return "", fmt.Errorf("unable to determine output format")
 }
 
+// sourceFormat returns the serialization format of a JSON/YAML source file or "srs" for a
+// sing-box binary rule-set, or "" for .dat input.
+func sourceFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return "json"
+	case ".yaml", ".yml":
+		return "yaml"
+	case ".srs":
+		return "srs"
+	default:
+		return ""
+	}
+}
+
+// decodeInput decodes raw input bytes as a JSON/YAML source, a sing-box rule-set or a .dat file.
+func decodeInput(path string, data []byte, isGeoSite bool) (map[string][]string, error) {
+	if f := sourceFormat(path); f == "srs" || (f == "json" && ruleset.IsSource(data)) {
+		return decodeRuleSet(path, data, isGeoSite)
+	}
+	if f := sourceFormat(path); f != "" {
+		result, err := format.Deserialize(data, f)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing %s source: %w", f, err)
+		}
+		return result, nil
+	}
+	if isGeoSite {
+		result, err := geosite.Decode(data)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding as geosite.dat: %w", err)
+		}
+		return result, nil
+	}
+	result, err := geoip.Decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding as geoip.dat: %w", err)
+	}
+	return result, nil
+}
+
+// decodePartial decodes a .dat input up to its first corrupted byte. It returns the entries
+// decoded so far together with the decode error, which is nil for intact input.
+func decodePartial(data []byte, isGeoSite bool) (map[string][]string, error) {
+	if isGeoSite {
+		return geosite.DecodePartial(data)
+	}
+	return geoip.DecodePartial(data)
+}
+
+// decodeRuleSet decodes a sing-box rule-set into a single entry named after the file:
+// "geosite-google.srs" becomes the tag "google" and "geoip-us.srs" the country "US".
+func decodeRuleSet(path string, data []byte, isGeoSite bool) (map[string][]string, error) {
+	rs, err := ruleset.Decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding sing-box rule-set: %w", err)
+	}
+	result, err := ruleset.Entries(rs, ruleSetTag(path, isGeoSite), isGeoSite)
+	if err != nil {
+		return nil, fmt.Errorf("error converting sing-box rule-set: %w", err)
+	}
+	return result, nil
+}
+
+// ruleSetTag derives an entry name from a rule-set file name.
+func ruleSetTag(path string, isGeoSite bool) string {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	if isGeoSite {
+		return strings.TrimPrefix(name, "geosite-")
+	}
+	return strings.ToUpper(strings.TrimPrefix(name, "geoip-"))
+}
+
+// loadInput reads and fully decodes an input file.
+func loadInput(path string, isGeoSite bool) (map[string][]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading input file: %w", err)
+	}
+	return decodeInput(path, data, isGeoSite)
+}
+
+// indexInput builds a tag index of a .dat file without decoding its entries.
+func indexInput(data []byte, isGeoSite bool) ([]geodata.IndexEntry, error) {
+	if isGeoSite {
+		index, err := geosite.Index(data)
+		if err != nil {
+			return nil, fmt.Errorf("error indexing geosite.dat: %w", err)
+		}
+		return index, nil
+	}
+	index, err := geoip.Index(data)
+	if err != nil {
+		return nil, fmt.Errorf("error indexing geoip.dat: %w", err)
+	}
+	return index, nil
+}
+
+// decodeSelected decodes only the .dat entries accepted by want, using the tag index
+// so that unrelated entries are never materialized.
+func decodeSelected(data []byte, isGeoSite bool, want func(string) bool) (map[string][]string, error) {
+	index, err := indexInput(data, isGeoSite)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string][]string)
+	for _, entry := range index {
+		if !want(entry.Tag) {
+			continue
+		}
+		var key string
+		var values []string
+		if isGeoSite {
+			key, values, err = geosite.DecodeEntry(data, entry)
+		} else {
+			key, values, err = geoip.DecodeEntry(data, entry)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error decoding entry %s: %w", entry.Tag, err)
+		}
+		result[key] = values
+	}
+	return result, nil
+}
+
+// loadInputs decodes every input and merges them according to policy. With a non-nil
+// want, .dat inputs are decoded through the tag index and only accepted entries are read.
+// With a non-nil onCorrupt, a corrupted .dat input keeps the entries decoded before the
+// corruption point and its error is passed to onCorrupt instead of failing the load.
+func loadInputs(paths []string, isGeoSite bool, want func(string) bool, policy *mergePolicy, onCorrupt func(path string, kept int, err error)) (map[string][]string, error) {
+	results := make([]map[string][]string, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading input file: %w", err)
+		}
+		var result map[string][]string
+		if onCorrupt != nil && sourceFormat(path) == "" {
+			result, err = decodePartial(data, isGeoSite)
+			if err != nil {
+				onCorrupt(path, len(result), err)
+				err = nil
+			}
+		} else if want != nil && sourceFormat(path) == "" {
+			result, err = decodeSelected(data, isGeoSite, want)
+		} else {
+			result, err = decodeInput(path, data, isGeoSite)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		results = append(results, result)
+	}
+	if len(results) == 1 {
+		return results[0], nil
+	}
+	return mergeData(paths, results, policy)
+}
+
+// listInputTags returns the tags or country codes of the input in file order.
+// For .dat input only the index is built; entries are not decoded. With a non-nil
+// onCorrupt, a corrupted .dat input lists the entries before the corruption point.
+func listInputTags(path string, data []byte, isGeoSite bool, onCorrupt func(path string, kept int, err error)) ([]string, error) {
+	if sourceFormat(path) != "" {
+		decoded, err := decodeInput(path, data, isGeoSite)
+		if err != nil {
+			return nil, err
+		}
+		tags := make([]string, 0, len(decoded))
+		for tag := range decoded {
+			tags = append(tags, tag)
+		}
+		return tags, nil
+	}
+	if onCorrupt != nil {
+		var tags []string
+		r := newEntryReader(bytes.NewReader(data), isGeoSite)
+		for r.Next() {
+			key, _ := r.Entry()
+			tags = append(tags, key)
+		}
+		if err := r.Err(); err != nil {
+			if key, values := r.Entry(); key != "" && len(values) > 0 {
+				tags = append(tags, key)
+			}
+			onCorrupt(path, len(tags), err)
+		}
+		return tags, nil
+	}
+	index, err := indexInput(data, isGeoSite)
+	if err != nil {
+		return nil, err
+	}
+	tags := make([]string, 0, len(index))
+	for _, entry := range index {
+		tags = append(tags, entry.Tag)
+	}
+	return tags, nil
+}
+
+// serializeOutput converts data to the output format, encoding .dat files with the matching
+// encoder, sing-box rule-sets (source or .srs) with the domain or IP CIDR fields and
+// Mihomo rule-providers (YAML, text or .mrs) with behavior domain or ipcidr, and
+// domain-list-community data files.
+func serializeOutput(data map[string][]string, outFormat string, isGeoSite bool) ([]byte, error) {
+	switch outFormat {
+	case "dat":
+		if isGeoSite {
+			return geosite.Encode(data)
+		}
+		return geoip.Encode(data)
+	case "singbox":
+		return format.SerializeSingBox(data, isGeoSite)
+	case "srs":
+		return format.SerializeSRS(data, isGeoSite)
+	case "mihomo-yaml":
+		return format.SerializeMihomoYAML(data, isGeoSite)
+	case "mihomo-text":
+		return format.SerializeMihomoText(data, isGeoSite)
+	case "mrs":
+		return format.SerializeMRS(data, isGeoSite)
+	case "dlc":
+		return format.SerializeDLC(data, isGeoSite)
+	default:
+		return format.Serialize(data, outFormat)
+	}
+}
+
 func parseList(listStr string, toUpper bool) []string {
 	if listStr == "" {
 		return nil
@@ -80,23 +344,27 @@ func parseList(listStr string, toUpper bool) []string {
 	return items
 }
 
-// sortMapByKeys sorts a map by keys and returns both keys and a new map with sorted entries.
-func sortMapByKeys(data map[string][]string, sortValues bool) ([]string, map[string][]string) {
-	keys := make([]string, 0, len(data))
-	for k := range data {
-		keys = append(keys, k)
+// parseTagSelector splits an Xray-style selector such as "google@cn@!ads" into
+// the tag name and its attribute selectors.
+func parseTagSelector(selector string) (string, []string) {
+	parts := strings.Split(selector, "@")
+	selectors := make([]string, 0, len(parts)-1)
+	for _, p := range parts[1:] {
+		if p != "" {
+			selectors = append(selectors, p)
+		}
 	}
-	sort.Strings(keys)
+	return parts[0], selectors
+}
 
-	sorted := make(map[string][]string)
-	for _, k := range keys {
-		vals := data[k]
-		if sortValues {
-			sort.Strings(vals)
+// firstTagSelector returns the first tag that has attribute selectors, or "".
+func firstTagSelector(tags []string) string {
+	for _, t := range tags {
+		if _, selectors := parseTagSelector(t); len(selectors) > 0 {
+			return t
 		}
-		sorted[k] = vals
 	}
-	return keys, sorted
+	return ""
 }
 
 func writeFileSafe(path string, data []byte) error {
@@ -109,69 +377,375 @@ func writeFileSafe(path string, data []byte) error {
 	return os.WriteFile(path, data, 0o644)
 }
 
-// exportToDirectory writes each key-value pair to a separate file in the output directory.
-func exportToDirectory(outputDir, outFormat string, filtered map[string][]string) error {
+// entryReader is the streaming interface shared by geoip.Reader and geosite.Reader.
+type entryReader interface {
+	Next() bool
+	Entry() (string, []string)
+	Err() error
+}
+
+func newEntryReader(r io.Reader, isGeoSite bool) entryReader {
+	if isGeoSite {
+		return geosite.NewReader(r)
+	}
+	return geoip.NewReader(r)
+}
+
+// dirExporter writes entries to separate files in an output directory using a bounded
+// pool of writers. write blocks while the pool is full, so callers streaming entries
+// never hold more than the pool size in memory.
+type dirExporter struct {
+	dir         string
+	format      string
+	ext         string
+	prefix      string
+	lower       bool
+	isGeoSite   bool
+	splitFamily bool
+
+	wg    sync.WaitGroup
+	sem   chan struct{}
+	mu    sync.Mutex
+	errs  []error
+	count int
+}
+
+func newDirExporter(outputDir, outFormat string, isGeoSite, splitFamily bool) (*dirExporter, error) {
 	if err := os.MkdirAll(outputDir, 0o755); err != nil {
-		return fmt.Errorf("create output directory: %w", err)
+		return nil, fmt.Errorf("create output directory: %w", err)
 	}
 
-	ext := "yaml"
-	if outFormat == "json" {
+	ext, prefix, lower := "yaml", "", false
+	switch outFormat {
+	case "json", "dat":
+		ext = outFormat
+	case "singbox":
 		ext = "json"
+	case "srs":
+		// Follow the sing-geosite/sing-geoip naming: geosite-google.srs, geoip-us.srs.
+		ext, prefix, lower = "srs", "geoip-", true
+		if isGeoSite {
+			prefix = "geosite-"
+		}
+	case "mihomo-yaml", "mihomo-text", "mrs":
+		// Follow the meta-rules-dat naming: google.mrs, cn.list, us.yaml.
+		ext, lower = outFormat, true
+		switch outFormat {
+		case "mihomo-yaml":
+			ext = "yaml"
+		case "mihomo-text":
+			ext = "list"
+		}
+	case "dlc":
+		// domain-list-community reads data/{tag} files without an extension.
+		ext, lower = "", true
 	}
 
-	var wg sync.WaitGroup
-	sem := make(chan struct{}, 32)
-	var mu sync.Mutex
-	var errs []error
+	return &dirExporter{
+		dir:         outputDir,
+		format:      outFormat,
+		ext:         ext,
+		prefix:      prefix,
+		lower:       lower,
+		isGeoSite:   isGeoSite,
+		splitFamily: splitFamily && !isGeoSite,
+		sem:         make(chan struct{}, 32),
+	}, nil
+}
+
+func (e *dirExporter) fail(err error) {
+	e.mu.Lock()
+	e.errs = append(e.errs, err)
+	e.mu.Unlock()
+}
+
+// write serializes a single entry to DIR/{key}.{ext} in the background. With family
+// splitting, IPv4 and IPv6 CIDRs go to DIR/{key}.v4.{ext} and DIR/{key}.v6.{ext}
+// instead, skipping a family the entry has no CIDRs for.
+func (e *dirExporter) write(key string, values []string) {
+	if !e.splitFamily {
+		e.writeAs(key, key, values)
+		return
+	}
+	v4, v6, err := geoip.SplitFamilies(values)
+	if err != nil {
+		e.fail(fmt.Errorf("split %s: %w", key, err))
+		return
+	}
+	if len(v4) > 0 {
+		e.writeAs(key+".v4", key, v4)
+	}
+	if len(v6) > 0 {
+		e.writeAs(key+".v6", key, v6)
+	}
+}
 
+// writeAs serializes a single entry to DIR/{name}.{ext} in the background, or to
+// DIR/geosite-{name}.srs / DIR/geoip-{name}.srs with a lower-case name for .srs output.
+// Mihomo rule-providers and domain-list-community files (DIR/{name}, without an
+// extension) also use lower-case names.
+func (e *dirExporter) writeAs(name, key string, values []string) {
+	e.sem <- struct{}{}
+	e.count++
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		defer func() { <-e.sem }()
+
+		single := map[string][]string{key: values}
+		data, err := serializeOutput(single, e.format, e.isGeoSite)
+		if err != nil {
+			e.fail(fmt.Errorf("serialize %s: %w", key, err))
+			return
+		}
+		if e.lower {
+			name = strings.ToLower(name)
+		}
+		filename := e.prefix + name
+		if e.ext != "" {
+			filename += "." + e.ext
+		}
+		path := filepath.Join(e.dir, filename)
+		if err := writeFileSafe(path, data); err != nil {
+			e.fail(fmt.Errorf("write %s: %w", path, err))
+		}
+	}()
+}
+
+// wait blocks until all pending writes finish and returns the first error.
+func (e *dirExporter) wait() error {
+	e.wg.Wait()
+	if len(e.errs) > 0 {
+		return e.errs[0]
+	}
+	return nil
+}
+
+// exportToDirectory writes each key-value pair to a separate file in the output directory.
+func exportToDirectory(outputDir, outFormat string, filtered map[string][]string, isGeoSite bool) error {
+	exp, err := newDirExporter(outputDir, outFormat, isGeoSite, *splitFamily)
+	if err != nil {
+		return err
+	}
 	for key, entries := range filtered {
-		wg.Add(1)
-		go func(k string, v []string) {
-			defer wg.Done()
-			sem <- struct{}{}
-			defer func() { <-sem }()
-
-			single := map[string][]string{k: v}
-			data, err := format.Serialize(single, outFormat)
-			if err != nil {
-				mu.Lock()
-				errs = append(errs, fmt.Errorf("serialize %s: %w", k, err))
-				mu.Unlock()
-				return
-			}
-			filename := fmt.Sprintf("%s.%s", k, ext)
-			path := filepath.Join(outputDir, filename)
-			if err := writeFileSafe(path, data); err != nil {
-				mu.Lock()
-				errs = append(errs, fmt.Errorf("write %s: %w", path, err))
-				mu.Unlock()
-			}
-		}(key, entries)
+		exp.write(key, entries)
+	}
+	if err := exp.wait(); err != nil {
+		return err
+	}
+	fmt.Printf("✅ Exported %d files to %s (%s)\n", exp.count, outputDir, outFormat)
+	return nil
+}
+
+// streamToDirectory decodes a .dat input one entry at a time and writes every selected
+// entry as soon as it is decoded, so the whole file is never held in memory. With
+// --tag or --country, only the selected entries are decoded, through the tag index.
+// It returns the number of files written. With --partial, a corrupted input is exported
+// up to the corruption point and reported as a warning.
+func streamToDirectory(inputPath, outputDir, outFormat string, filter *entryFilter) (int, error) {
+	exp, err := newDirExporter(outputDir, outFormat, filter.isGeoSite, *splitFamily)
+	if err != nil {
+		return 0, err
 	}
-	wg.Wait()
+	emit := exp.write
+	if filter.isGeoSite && isMihomoFormat(outFormat) {
+		emit = func(key string, values []string) {
+			warnClassical(filter, key, values)
+			exp.write(key, values)
+		}
+	}
+	var readErr error
+	if filter.active() && !*partialFlag {
+		readErr = exportSelected(inputPath, filter, emit)
+	} else {
+		readErr = exportStream(inputPath, filter, emit)
+	}
+	writeErr := exp.wait()
+	if readErr != nil {
+		return 0, readErr
+	}
+	if writeErr != nil {
+		return 0, writeErr
+	}
+	return exp.count, filter.done()
+}
 
-	if len(errs) > 0 {
-		return errs[0]
+// exportSelected passes the entries of a .dat input selected by filter to emit,
+// decoding only those entries found in the tag index.
+func exportSelected(inputPath string, filter *entryFilter, emit func(string, []string)) error {
+	data, err := os.ReadFile(inputPath)
+	if err != nil {
+		return fmt.Errorf("error reading input file: %w", err)
+	}
+	index, err := indexInput(data, filter.isGeoSite)
+	if err != nil {
+		return err
+	}
+	for _, entry := range index {
+		if !filter.wants(entry.Tag) {
+			continue
+		}
+		var key string
+		var values []string
+		if filter.isGeoSite {
+			key, values, err = geosite.DecodeEntry(data, entry)
+		} else {
+			key, values, err = geoip.DecodeEntry(data, entry)
+		}
+		if err != nil {
+			return fmt.Errorf("error decoding entry %s: %w", entry.Tag, err)
+		}
+		filter.apply(key, values, emit)
 	}
-	fmt.Printf("✅ Exported %d files to %s (%s)\n", len(filtered), outputDir, outFormat)
 	return nil
 }
 
+// exportStream passes every entry of a .dat input to filter.apply as it is decoded.
+func exportStream(inputPath string, filter *entryFilter, emit func(string, []string)) error {
+	f, err := os.Open(inputPath)
+	if err != nil {
+		return fmt.Errorf("error reading input file: %w", err)
+	}
+	defer func() { _ = f.Close() }() // read-only, nothing to flush
+
+	r := newEntryReader(f, filter.isGeoSite)
+	entries := 0
+	for r.Next() {
+		key, values := r.Entry()
+		filter.apply(key, values, emit)
+		entries++
+	}
+	err = r.Err()
+	if err != nil && *partialFlag {
+		if key, values := r.Entry(); key != "" && len(values) > 0 {
+			filter.apply(key, values, emit)
+			entries++
+		}
+		filter.warn("%s: kept %d entries decoded before the error: %v", inputPath, entries, err)
+		return nil
+	}
+	if err != nil {
+		if filter.isGeoSite {
+			return fmt.Errorf("error decoding as geosite.dat: %w", err)
+		}
+		return fmt.Errorf("error decoding as geoip.dat: %w", err)
+	}
+	return nil
+}
+
+// warnClassical warns about the "keyword:" and "regexp:" rules of tag, which a Mihomo
+// rule-provider with behavior domain cannot hold and which are left out of its payload.
+func warnClassical(filter *entryFilter, tag string, rules []string) {
+	_, classical, err := format.MihomoPayload(map[string][]string{tag: rules}, true)
+	if err != nil || len(classical) == 0 {
+		return // invalid rules are reported when serializing
+	}
+	shown := classical[:min(len(classical), maxListed)]
+	more := ""
+	if len(classical) > len(shown) {
+		more = fmt.Sprintf(" and %d more", len(classical)-len(shown))
+	}
+	filter.warn("%s: %d rules need behavior classical and were left out: %s%s",
+		tag, len(classical), strings.Join(shown, ", "), more)
+}
+
+// describeError formats an error for the command line. Decode errors are spelled out
+// with their location so the corrupted part of the input can be inspected.
+func describeError(err error) string {
+	var de *geodata.DecodeError
+	if !errors.As(err, &de) {
+		return err.Error()
+	}
+	var b strings.Builder
+	b.WriteString(err.Error())
+	fmt.Fprintf(&b, "\n  offset: %d (0x%x)", de.Offset, de.Offset)
+	if de.Tag != "" {
+		fmt.Fprintf(&b, "\n  tag:    %s", de.Tag)
+	}
+	if de.Index >= 0 {
+		fmt.Fprintf(&b, "\n  record: %d", de.Index)
+	}
+	fmt.Fprintf(&b, "\n  cause:  %v", de.Err)
+	return b.String()
+}
+
+// fatalInput exits with a description of an error reading the inputs, suggesting
+// --partial when the input is corrupted.
+func fatalInput(prefix string, err error) {
+	msg := describeError(err)
+	var de *geodata.DecodeError
+	if errors.As(err, &de) && !*partialFlag {
+		msg += "\n  hint:   rerun with --partial to keep the entries decoded before this point"
+	}
+	log.Fatal(prefix, msg)
+}
+
+// printWarnings outputs collected warnings to stderr.
+func printWarnings(warnings []string) {
+	for _, w := range warnings {
+		fmt.Fprintf(os.Stderr, "⚠️ Warning: %s\n", w)
+	}
+}
+
+// printSummary outputs informational lines about applied transformations to stderr.
+func printSummary(lines []string) {
+	for _, l := range lines {
+		fmt.Fprintf(os.Stderr, "ℹ️ %s\n", l)
+	}
+}
+
+// subcommands maps subcommand names to their implementations.
+var subcommands = map[string]func(args []string) error{
+	"diff":     func(args []string) error { return runDiff(args, os.Stdout) },
+	"lookup":   func(args []string) error { return runLookup(args, os.Stdin, os.Stdout) },
+	"match":    func(args []string) error { return runMatch(args, os.Stdin, os.Stdout) },
+	"validate": func(args []string) error { return runValidate(args, os.Stdout) },
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if run, ok := subcommands[os.Args[1]]; ok {
+			err := run(os.Args[2:])
+			if errors.Is(err, flag.ErrHelp) {
+				return
+			}
+			if err != nil {
+				log.Fatal("error: ", describeError(err))
+			}
+			return
+		}
+	}
+
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Usage: %s -i input.dat [options]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s lookup -i geoip.dat [IP...]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s match --site -i geosite.dat [HOST...]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s diff --ip|--site [--format text|json|yaml] OLD NEW\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s validate --ip|--site -i FILE [--format text|json|yaml] [--strict]\n", os.Args[0])
 		fmt.Fprintln(os.Stderr, "\nOptions:")
-		fmt.Fprintln(os.Stderr, "  -i FILE             Input .dat file (required)")
+		fmt.Fprintln(os.Stderr, "  -i FILE             Input .dat file, .json/.yaml/.yml source or sing-box rule-set (.srs/.json; required, repeatable)")
 		fmt.Fprintln(os.Stderr, "  --ip                Treat input as geoip.dat")
 		fmt.Fprintln(os.Stderr, "  --site              Treat input as geosite.dat")
-		fmt.Fprintln(os.Stderr, "  -o FILE             Output file (.json/.yaml/.yml)")
+		fmt.Fprintln(os.Stderr, "  -o FILE             Output file (.json/.yaml/.yml/.dat/.srs/.mrs)")
 		fmt.Fprintln(os.Stderr, "  --output-dir DIR    Output each tag/country to separate file")
-		fmt.Fprintln(os.Stderr, "  --format FMT        Output format: json or yaml")
-		fmt.Fprintln(os.Stderr, "  --tag LIST          Filter geosite by tags")
+		fmt.Fprintln(os.Stderr, "  --format FMT        Output format: json, yaml, dat, singbox or srs (sing-box rule-sets),")
+		fmt.Fprintln(os.Stderr, "                      mihomo-yaml, mihomo-text or mrs (Mihomo rule-providers),")
+		fmt.Fprintln(os.Stderr, "                      dlc (domain-list-community data files; --output-dir and --site only)")
+		fmt.Fprintln(os.Stderr, "  --tag LIST          Filter geosite by tags (tag@attr keeps, tag@!attr drops attributed rules)")
 		fmt.Fprintln(os.Stderr, "  --country LIST      Filter geoip by country codes")
-		fmt.Fprintln(os.Stderr, "  --list-tags         List all tags in geosite.dat and exit")
+		fmt.Fprintln(os.Stderr, "  --list-tags         List all tags/countries in the input and exit")
 		fmt.Fprintln(os.Stderr, "  --sort              Sort keys")
+		fmt.Fprintln(os.Stderr, "  --aggregate         Merge overlapping and adjacent CIDRs (geoip only)")
+		fmt.Fprintln(os.Stderr, "  --canonical         Mask host bits in CIDRs (1.2.3.4/24 → 1.2.3.0/24; geoip only)")
+		fmt.Fprintln(os.Stderr, "  --prune             Drop duplicate and subsumed rules within each tag (geosite only)")
+		fmt.Fprintln(os.Stderr, "  --family FAMILY     Keep only ipv4 or ipv6 CIDRs, or both (default; geoip only)")
+		fmt.Fprintln(os.Stderr, "  --split-family      With --output-dir, write {name}.v4 and {name}.v6 files (geoip only)")
+		fmt.Fprintln(os.Stderr, "  --expr EXPR         Set expression over countries or tags: | union, & intersect, ' - ' subtract,")
+		fmt.Fprintln(os.Stderr, "                      ! complement (geoip only), parentheses")
+		fmt.Fprintln(os.Stderr, "  --expr-tag NAME     Name of the entry produced by --expr (default \"expr\")")
+		fmt.Fprintln(os.Stderr, "  --partial           Keep the entries decoded before a corrupted part of a .dat input")
+		fmt.Fprintln(os.Stderr, "  --merge POLICY      Conflict policy for repeated -i: union, prefer-first, prefer-last, error")
+		fmt.Fprintln(os.Stderr, "                      (per tag: union,google=prefer-last)")
 		fmt.Fprintln(os.Stderr, "  -h                  Show this help")
 	}
 	flag.Parse()
@@ -181,7 +755,7 @@ func main() {
 		os.Exit(0)
 	}
 
-	if *inputFile == "" {
+	if len(*inputFiles) == 0 {
 		log.Fatal("error: -i input file is required")
 	}
 
@@ -206,117 +780,156 @@ func main() {
 		log.Fatal("error:", err)
 	}
 
-	data, err := os.ReadFile(*inputFile)
+	if outFormat == "dlc" && !*listTags {
+		if *outputDir == "" {
+			log.Fatal("error: --format dlc requires --output-dir")
+		}
+		if !*siteMode {
+			log.Fatal("error: --format dlc is geosite only")
+		}
+	}
+
+	if (outFormat == "dat" || outFormat == "dlc") && *siteMode && !*listTags {
+		if t := firstTagSelector(parseList(*tagFilter, false)); t != "" {
+			log.Fatalf("error: --tag '%s': attribute selectors cannot be used with --format %s, whose tag names cannot contain '@'", t, outFormat)
+		}
+	}
+
+	if !isValidFamily(*familyFlag) {
+		log.Fatal("error: --family must be 'ipv4', 'ipv6' or 'both'")
+	}
+
+	if *splitFamily && *outputDir == "" && !*listTags {
+		log.Fatal("error: --split-family requires --output-dir")
+	}
+
+	policy, err := parseMergePolicy(*mergeFlag)
 	if err != nil {
-		log.Fatal("error reading input file:", err)
+		log.Fatal("error: ", err)
 	}
 
-	if len(data) == 0 {
-		log.Fatal("error: input file is empty")
+	inputs := *inputFiles
+	for _, path := range inputs {
+		info, err := os.Stat(path)
+		if err != nil {
+			log.Fatal("error reading input file:", err)
+		}
+		if info.Size() == 0 {
+			log.Fatalf("error: input file %s is empty", path)
+		}
 	}
 
 	isGeoSite := *siteMode
-	var fullResult map[string][]string
-	var decodeErr error
+	filter := newEntryFilter(isGeoSite)
 
-	if *ipMode {
-		fullResult, decodeErr = geoip.Decode(data)
-		if decodeErr != nil {
-			log.Fatalf("error decoding as geoip.dat: %v", decodeErr)
+	var onCorrupt func(string, int, error)
+	if *partialFlag {
+		onCorrupt = func(path string, kept int, err error) {
+			filter.warn("%s: kept %d entries decoded before the error: %v", path, kept, err)
 		}
-	} else if *siteMode {
-		fullResult, decodeErr = geosite.Decode(data)
-		if decodeErr != nil {
-			log.Fatalf("error decoding as geosite.dat: %v", decodeErr)
+	}
+
+	var query *exprQuery
+	if *exprFlag != "" && !*listTags {
+		if filter.active() {
+			log.Fatal("error: --expr cannot be combined with --tag or --country")
+		}
+		query, err = parseExprQuery(*exprFlag, *exprTag, isGeoSite)
+		if err != nil {
+			log.Fatal("error: ", err)
+		}
+	}
+
+	// Stream a single .dat input straight to per-entry files without decoding it all up front.
+	if len(inputs) == 1 && *outputDir != "" && !*listTags && query == nil && sourceFormat(inputs[0]) == "" {
+		count, err := streamToDirectory(inputs[0], *outputDir, outFormat, filter)
+		printWarnings(filter.warnings)
+		if err != nil {
+			fatalInput("error exporting to directory: ", err)
 		}
+		printSummary(filter.summary())
+		fmt.Printf("✅ Exported %d files to %s (%s)\n", count, *outputDir, outFormat)
+		return
 	}
 
 	// Handle --list-tags flag: display all tags in the data.
 	if *listTags {
-		if !isGeoSite {
-			log.Fatal("--list-tags is only supported for geosite.dat (--site)")
-		}
-		tags := make([]string, 0, len(fullResult))
-		for tag := range fullResult {
-			tags = append(tags, tag)
+		var tags []string
+		for _, path := range inputs {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				log.Fatal("error reading input file:", err)
+			}
+			inputTags, err := listInputTags(path, data, isGeoSite, onCorrupt)
+			if err != nil {
+				fatalInput("", err)
+			}
+			tags = append(tags, inputTags...)
 		}
+		tags = uniqueTags(tags)
 		if *sortKeys {
 			sort.Strings(tags)
 		}
+		printWarnings(filter.warnings)
 		for _, tag := range tags {
 			fmt.Println(tag)
 		}
 		os.Exit(0)
 	}
 
-	// Apply tag/country filters if provided, otherwise use all entries.
-	var filtered map[string][]string
-	warnings := []string{}
+	// Decode (only the requested entries when filtering) and merge all inputs.
+	var want func(string) bool
+	if query != nil {
+		want = query.wants
+	} else if filter.active() {
+		want = filter.wants
+	}
+	fullResult, err := loadInputs(inputs, isGeoSite, want, policy, onCorrupt)
+	if err != nil {
+		fatalInput("", err)
+	}
 
-	if isGeoSite {
-		if *countryFilter != "" {
-			warnings = append(warnings, "--country is ignored for geosite.dat")
-		}
-		if *tagFilter != "" {
-			tags := parseList(*tagFilter, false)
-			filtered = make(map[string][]string)
-			lowerMap := make(map[string]string)
-			for k := range fullResult {
-				lowerMap[strings.ToLower(k)] = k
-			}
-			for _, t := range tags {
-				if origTag, ok := lowerMap[t]; ok {
-					filtered[origTag] = fullResult[origTag]
-				} else {
-					warnings = append(warnings, fmt.Sprintf("tag '%s' not found", t))
-				}
-			}
-			if len(filtered) == 0 {
-				log.Fatal("error: no valid tags found")
-			}
-		} else {
-			filtered = fullResult
-		}
-		if *sortKeys {
-			_, filtered = sortMapByKeys(filtered, true)
+	// Replace the entries with the result of --expr, if given.
+	if query != nil {
+		var notes []string
+		fullResult, notes, err = query.eval(fullResult)
+		if err != nil {
+			log.Fatal("error: ", err)
 		}
-	} else {
-		if *tagFilter != "" {
-			warnings = append(warnings, "--tag is ignored for geoip.dat")
-		}
-		if *countryFilter != "" {
-			countries := parseList(*countryFilter, true)
-			filtered = make(map[string][]string)
-			for _, code := range countries {
-				if cidrs, ok := fullResult[code]; ok {
-					filtered[code] = cidrs
-				} else {
-					warnings = append(warnings, fmt.Sprintf("country code '%s' not found", code))
-				}
+		for i, n := range notes {
+			if i == maxListed {
+				filter.warn("imprecise: %d more rules not shown", len(notes)-i)
+				break
 			}
-			if len(filtered) == 0 {
-				log.Fatal("error: no valid country codes found")
-			}
-		} else {
-			filtered = fullResult
-		}
-		if *sortKeys {
-			_, filtered = sortMapByKeys(filtered, false)
+			filter.warn("imprecise: %s", n)
 		}
 	}
 
-	// Output any collected warnings to stderr.
-	for _, w := range warnings {
-		fmt.Fprintf(os.Stderr, "⚠️ Warning: %s\n", w)
+	// Apply tag/country filters if provided, otherwise use all entries.
+	filtered, err := filter.filterAll(fullResult)
+	if err == nil && isGeoSite && isMihomoFormat(outFormat) {
+		tags := make([]string, 0, len(filtered))
+		for tag := range filtered {
+			tags = append(tags, tag)
+		}
+		sort.Strings(tags)
+		for _, tag := range tags {
+			warnClassical(filter, tag, filtered[tag])
+		}
 	}
+	printWarnings(filter.warnings)
+	if err != nil {
+		log.Fatal("error: ", err)
+	}
+	printSummary(filter.summary())
 
 	// Export: write data to output file or directory.
 	if *outputDir != "" {
-		if err := exportToDirectory(*outputDir, outFormat, filtered); err != nil {
+		if err := exportToDirectory(*outputDir, outFormat, filtered, isGeoSite); err != nil {
 			log.Fatalf("error exporting to directory: %v", err)
 		}
 	} else if *outputFile != "" {
-		outBytes, err := format.Serialize(filtered, outFormat)
+		outBytes, err := serializeOutput(filtered, outFormat, isGeoSite)
 		if err != nil {
 			log.Fatal("error serializing output:", err)
 		}
@@ -329,9 +942,27 @@ func main() {
 		} else if !isGeoSite && *countryFilter != "" {
 			desc += " (countries: " + *countryFilter + ")"
 		}
+		if query != nil {
+			desc += " (expr " + query.name + ": " + *exprFlag + ")"
+		}
 		if *sortKeys {
 			desc += " + sorted"
 		}
-		fmt.Printf("✅ Successfully converted %s → %s (%s)\n", *inputFile, *outputFile, desc)
+		if filter.family != geoip.FamilyBoth {
+			desc += " + " + filter.family + " only"
+		}
+		if filter.canonical {
+			desc += " + canonical"
+		}
+		if filter.aggregate {
+			desc += " + aggregated"
+		}
+		if filter.prune {
+			desc += " + pruned"
+		}
+		if len(inputs) > 1 {
+			desc += " + merged (" + *mergeFlag + ")"
+		}
+		fmt.Printf("✅ Successfully converted %s → %s (%s)\n", strings.Join(inputs, ", "), *outputFile, desc)
 	}
 }
@@ -2,13 +2,20 @@
 package main
 
 import (
+	"bytes"
+	"errors"
 	"flag"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
-	"dat2json/internal/geodata/router"
+	"github.com/Viktor45/dat2json/internal/geodata"
+	"github.com/Viktor45/dat2json/internal/geodata/router"
+	"github.com/Viktor45/dat2json/internal/geoip"
+	"github.com/Viktor45/dat2json/pkg/format"
 
 	"google.golang.org/protobuf/proto"
 )
@@ -17,7 +24,7 @@ import (
 func resetFlags() {
 	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
 	// Recreate all flags with their default values.
-	inputFile = flag.String("i", "", "")
+	inputFiles = newStringList("i", "")
 	outputFile = flag.String("o", "", "")
 	outputDir = flag.String("output-dir", "", "")
 	tagFilter = flag.String("tag", "", "")
@@ -27,6 +34,15 @@ func resetFlags() {
 	formatFlag = flag.String("format", "", "")
 	ipMode = flag.Bool("ip", false, "")
 	siteMode = flag.Bool("site", false, "")
+	aggregate = flag.Bool("aggregate", false, "")
+	familyFlag = flag.String("family", geoip.FamilyBoth, "")
+	splitFamily = flag.Bool("split-family", false, "")
+	exprFlag = flag.String("expr", "", "")
+	exprTag = flag.String("expr-tag", "expr", "")
+	pruneFlag = flag.Bool("prune", false, "")
+	canonical = flag.Bool("canonical", false, "")
+	partialFlag = flag.Bool("partial", false, "")
+	mergeFlag = flag.String("merge", mergeUnion, "")
 	help = flag.Bool("h", false, "")
 }
 
@@ -103,3 +119,373 @@ func TestIntegrationGeoSiteProtobuf(t *testing.T) {
 		t.Errorf("unexpected output: %s", string(content))
 	}
 }
+
+func TestIntegrationGeoIPReverse(t *testing.T) {
+	resetFlags()
+	inputFile := filepath.Join(t.TempDir(), "geoip.json")
+	outputFile := filepath.Join(t.TempDir(), "geoip.dat")
+
+	if err := os.WriteFile(inputFile, []byte(`{"US": ["1.2.3.0/24", "2001:db8::/32"]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	os.Args = []string{"dat2json", "-i", inputFile, "--ip", "-o", outputFile}
+	main()
+
+	content, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var list router.GeoIPList
+	if err := proto.Unmarshal(content, &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Entry) != 1 || list.Entry[0].CountryCode != "US" || len(list.Entry[0].Cidr) != 2 {
+		t.Errorf("unexpected output: %v", &list)
+	}
+}
+
+func TestIntegrationGeoSiteReverse(t *testing.T) {
+	resetFlags()
+	inputFile := filepath.Join(t.TempDir(), "geosite.yaml")
+	outputFile := filepath.Join(t.TempDir(), "geosite.dat")
+
+	source := "test:\n  - domain:example.com\n  - full:www.example.org\n"
+	if err := os.WriteFile(inputFile, []byte(source), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	os.Args = []string{"dat2json", "-i", inputFile, "--site", "-o", outputFile}
+	main()
+
+	content, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var list router.GeoSiteList
+	if err := proto.Unmarshal(content, &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Entry) != 1 || len(list.Entry[0].Domain) != 2 || list.Entry[0].Domain[1].Type != router.Domain_Full {
+		t.Errorf("unexpected output: %v", &list)
+	}
+}
+
+func TestParseTagSelector(t *testing.T) {
+	tag, selectors := parseTagSelector("google@cn@!ads")
+	if tag != "google" || len(selectors) != 2 || selectors[0] != "cn" || selectors[1] != "!ads" {
+		t.Errorf("unexpected result: %q %v", tag, selectors)
+	}
+	tag, selectors = parseTagSelector("netflix")
+	if tag != "netflix" || len(selectors) != 0 {
+		t.Errorf("unexpected result: %q %v", tag, selectors)
+	}
+	if got := firstTagSelector([]string{"netflix", "google@", "google@cn"}); got != "google@cn" {
+		t.Errorf("unexpected selector: %q", got)
+	}
+	if got := firstTagSelector([]string{"netflix"}); got != "" {
+		t.Errorf("unexpected selector: %q", got)
+	}
+}
+
+func TestIntegrationGeoSiteAttributeFilter(t *testing.T) {
+	resetFlags()
+	inputFile := filepath.Join(t.TempDir(), "geosite.yaml")
+	outputFile := filepath.Join(t.TempDir(), "output.json")
+
+	source := "GOOGLE:\n  - domain:google.com\n  - domain:google.cn @cn\n"
+	if err := os.WriteFile(inputFile, []byte(source), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	os.Args = []string{"dat2json", "-i", inputFile, "--site", "-o", outputFile, "--tag", "google@cn,google@!cn"}
+	main()
+
+	content, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(content)
+	if !strings.Contains(out, `"GOOGLE@cn": [`+"\n"+`    "domain:google.cn @cn"`) ||
+		!strings.Contains(out, `"GOOGLE@!cn": [`+"\n"+`    "domain:google.com"`) {
+		t.Errorf("unexpected output: %s", out)
+	}
+}
+
+func TestIntegrationGeoIPReverseMatch(t *testing.T) {
+	resetFlags()
+	inputFile := filepath.Join(t.TempDir(), "geoip.json")
+	outputFile := filepath.Join(t.TempDir(), "output.json")
+
+	source := `{"!CN": ["1.0.0.0/24"], "US": ["2.0.0.0/24"]}`
+	if err := os.WriteFile(inputFile, []byte(source), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	os.Args = []string{"dat2json", "-i", inputFile, "--ip", "-o", outputFile, "--country", "cn"}
+	main()
+
+	content, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), `"!CN"`) || strings.Contains(string(content), `"US"`) {
+		t.Errorf("unexpected output: %s", string(content))
+	}
+}
+
+func TestIntegrationStreamToDirectory(t *testing.T) {
+	resetFlags()
+	outputDir := t.TempDir()
+
+	os.Args = []string{"dat2json", "-i", "example/zkeen-ip.dat", "--ip", "--output-dir", outputDir, "--format", "json", "--country", "ru,telegram"}
+	main()
+
+	entries, err := os.ReadDir(outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 files, got %d", len(entries))
+	}
+	content, err := os.ReadFile(filepath.Join(outputDir, "TELEGRAM.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), `"TELEGRAM"`) {
+		t.Errorf("unexpected output: %s", string(content))
+	}
+}
+
+func TestListInputTags(t *testing.T) {
+	data, err := os.ReadFile("example/zkeen-site.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tags, err := listInputTags("example/zkeen-site.dat", data, true, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(tags) != 6 || tags[0] != "BYPASS" {
+		t.Errorf("unexpected tags: %v", tags)
+	}
+}
+
+func TestDecodeSelected(t *testing.T) {
+	data, err := os.ReadFile("example/zkeen-ip.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := decodeSelected(data, false, func(code string) bool { return code == "TELEGRAM" })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result) != 1 || len(result["TELEGRAM"]) != 9 {
+		t.Errorf("unexpected result: %v", result)
+	}
+}
+
+func TestIntegrationMergeInputs(t *testing.T) {
+	resetFlags()
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.yaml")
+	outputFile := filepath.Join(dir, "merged.json")
+
+	if err := os.WriteFile(first, []byte("YOUTUBE:\n  - domain:example.com\nCUSTOM:\n  - full:a.example\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	os.Args = []string{"dat2json", "-i", "example/zkeen-site.dat", "-i", first, "--site", "-o", outputFile, "--merge", "prefer-first,custom=prefer-last"}
+	main()
+
+	content, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(content)
+	if !strings.Contains(out, `"CUSTOM"`) || !strings.Contains(out, `"BYPASS"`) || strings.Contains(out, "domain:example.com") {
+		t.Errorf("unexpected output: %s", out)
+	}
+}
+
+func TestIntegrationSplitFamily(t *testing.T) {
+	resetFlags()
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.json")
+	if err := os.WriteFile(input, []byte(`{"US": ["8.8.8.0/24", "2001:4860::/32"], "RU": ["5.3.0.0/16"]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	outputDir := filepath.Join(dir, "out")
+
+	os.Args = []string{"dat2json", "-i", input, "--ip", "--output-dir", outputDir, "--format", "json", "--split-family"}
+	main()
+
+	entries, err := os.ReadDir(outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if strings.Join(names, ",") != "RU.v4.json,US.v4.json,US.v6.json" {
+		t.Fatalf("unexpected files: %v", names)
+	}
+	content, err := os.ReadFile(filepath.Join(outputDir, "US.v6.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), `"US"`) || strings.Contains(string(content), "8.8.8.0/24") {
+		t.Errorf("unexpected output: %s", string(content))
+	}
+}
+
+func TestIntegrationPartialDecode(t *testing.T) {
+	resetFlags()
+	dir := t.TempDir()
+	inputFile := filepath.Join(dir, "geoip.dat")
+	outputFile := filepath.Join(dir, "output.json")
+
+	// US is complete; RU breaks off in the middle of its second record.
+	data := []byte("GEOI\x01\x02US\x01\x01\x02\x03\x04\x18\x02RU\x02\x05\x03\x00\x00\x10\x06")
+	if err := os.WriteFile(inputFile, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := loadInputs([]string{inputFile}, false, nil, nil, nil)
+	var de *geodata.DecodeError
+	if !errors.As(err, &de) {
+		t.Fatalf("expected DecodeError, got %v", err)
+	}
+	if de.Tag != "RU" || de.Index != 1 || de.Offset != int64(len(data)) {
+		t.Errorf("unexpected location: %+v", de)
+	}
+
+	os.Args = []string{"dat2json", "-i", inputFile, "--ip", "-o", outputFile, "--partial"}
+	main()
+
+	content, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(content)
+	if !strings.Contains(out, "1.2.3.4/24") || !strings.Contains(out, "5.3.0.0/16") {
+		t.Errorf("unexpected output: %s", out)
+	}
+}
+
+func TestDescribeError(t *testing.T) {
+	err := fmt.Errorf("geoip.dat: %w", &geodata.DecodeError{Offset: 24, Tag: "RU", Index: 1, Err: io.ErrUnexpectedEOF})
+	got := describeError(err)
+	for _, want := range []string{"offset: 24 (0x18)", "tag:    RU", "record: 1", "cause:  unexpected EOF"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in:\n%s", want, got)
+		}
+	}
+	if got := describeError(errors.New("plain")); got != "plain" {
+		t.Errorf("unexpected description: %s", got)
+	}
+}
+
+func TestIntegrationSingBoxDirectory(t *testing.T) {
+	resetFlags()
+	outputDir := t.TempDir()
+
+	os.Args = []string{"dat2json", "-i", "example/zkeen-site.dat", "--site", "--output-dir", outputDir, "--format", "singbox", "--tag", "youtube"}
+	main()
+
+	content, err := os.ReadFile(filepath.Join(outputDir, "YOUTUBE.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(content)
+	if !strings.Contains(out, `"version": 2`) || !strings.Contains(out, `"domain_suffix"`) || strings.Contains(out, "domain:") {
+		t.Errorf("unexpected output: %s", out)
+	}
+}
+
+func TestIntegrationSRSDirectory(t *testing.T) {
+	resetFlags()
+	outputDir := t.TempDir()
+
+	os.Args = []string{"dat2json", "-i", "example/zkeen-ip.dat", "--ip", "--output-dir", outputDir, "--format", "srs", "--country", "telegram"}
+	main()
+
+	content, err := os.ReadFile(filepath.Join(outputDir, "geoip-telegram.srs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(content, []byte("SRS\x02")) {
+		t.Errorf("unexpected header: %q", content[:4])
+	}
+}
+
+func TestIntegrationMihomoDirectory(t *testing.T) {
+	resetFlags()
+	outputDir := t.TempDir()
+
+	os.Args = []string{"dat2json", "-i", "example/zkeen-site.dat", "--site", "--output-dir", outputDir, "--format", "mihomo-text", "--tag", "youtube"}
+	main()
+
+	content, err := os.ReadFile(filepath.Join(outputDir, "youtube.list"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(content)
+	if !strings.Contains(out, "+.googlevideo.com\n") || strings.Contains(out, "keyword:") || strings.Contains(out, "domain:") {
+		t.Errorf("unexpected output: %s", out)
+	}
+}
+
+func TestIntegrationDLCDirectory(t *testing.T) {
+	resetFlags()
+	outputDir := t.TempDir()
+
+	os.Args = []string{"dat2json", "-i", "example/zkeen-site.dat", "--site", "--output-dir", outputDir, "--format", "dlc", "--tag", "youtube"}
+	main()
+
+	content, err := os.ReadFile(filepath.Join(outputDir, "youtube"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(content)
+	if !strings.HasPrefix(out, "googlevideo.com\n") || !strings.Contains(out, "\nkeyword:youtube\n") || strings.Contains(out, "domain:") {
+		t.Errorf("unexpected output: %s", out)
+	}
+}
+
+func TestIntegrationRuleSetInput(t *testing.T) {
+	resetFlags()
+	dir := t.TempDir()
+	input := filepath.Join(dir, "geosite-custom.srs")
+	outputFile := filepath.Join(dir, "output.json")
+
+	data, err := format.SerializeSRS(map[string][]string{"custom": {"domain:example.com", "full:www.example.org"}}, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := os.WriteFile(input, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	os.Args = []string{"dat2json", "-i", input, "--site", "-o", outputFile, "--tag", "custom", "--sort"}
+	main()
+
+	decoded, err := loadInput(outputFile, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"domain:example.com", "full:www.example.org"}
+	if got := decoded["custom"]; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("unexpected rules: %v", got)
+	}
+}
+
+func TestRuleSetTag(t *testing.T) {
+	if got := ruleSetTag("dir/geosite-google.srs", true); got != "google" {
+		t.Errorf("unexpected geosite tag: %s", got)
+	}
+	if got := ruleSetTag("geoip-us.json", false); got != "US" {
+		t.Errorf("unexpected geoip tag: %s", got)
+	}
+}
@@ -0,0 +1,78 @@
+// match.go
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/Viktor45/dat2json/internal/geosite"
+)
+
+// runMatch implements "dat2json match": it reports every geosite tag and rule matching
+// each hostname given on the command line, or read from stdin when none are given.
+func runMatch(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("match", flag.ContinueOnError)
+	input := fs.String("i", "", "Input geosite.dat file (or .json/.yaml/.yml source)")
+	site := fs.Bool("site", true, "Treat input as geosite.dat (the only supported input)")
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(fs.Output(), "Usage: %s match [--site] -i geosite.dat [HOST...]\n", os.Args[0])
+		_, _ = fmt.Fprintln(fs.Output(), "\nReads hostnames from stdin when none are given.")
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if !*site {
+		return fmt.Errorf("match only supports geosite input (use lookup for geoip.dat)")
+	}
+	if *input == "" {
+		return fmt.Errorf("-i input file is required")
+	}
+
+	decoded, err := loadInput(*input, true)
+	if err != nil {
+		return err
+	}
+	matcher, err := geosite.NewMatcher(decoded)
+	if err != nil {
+		return fmt.Errorf("error building matcher: %w", err)
+	}
+
+	match := func(host string) error {
+		_, err := fmt.Fprintln(stdout, formatSiteMatches(host, matcher.Match(host)))
+		return err
+	}
+
+	if fs.NArg() > 0 {
+		for _, arg := range fs.Args() {
+			if err := match(arg); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	scanner := bufio.NewScanner(stdin)
+	for scanner.Scan() {
+		for _, field := range strings.Fields(scanner.Text()) {
+			if err := match(field); err != nil {
+				return err
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// formatSiteMatches renders a match result as "HOST: TAG (RULE), ...".
+func formatSiteMatches(host string, matches []geosite.Match) string {
+	if len(matches) == 0 {
+		return host + ": no match"
+	}
+	parts := make([]string, 0, len(matches))
+	for _, m := range matches {
+		parts = append(parts, fmt.Sprintf("%s (%s)", m.Tag, m.Rule))
+	}
+	return host + ": " + strings.Join(parts, ", ")
+}
@@ -0,0 +1,45 @@
+// match_test.go
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunMatch(t *testing.T) {
+	inputFile := filepath.Join(t.TempDir(), "geosite.yaml")
+	source := "google:\n  - domain:google.com\n  - full:www.google.com\n"
+	if err := os.WriteFile(inputFile, []byte(source), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := runMatch([]string{"--site", "-i", inputFile, "www.google.com", "example.org"}, nil, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "www.google.com: google (domain:google.com), google (full:www.google.com)\nexample.org: no match\n"
+	if out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+}
+
+func TestRunMatchStdin(t *testing.T) {
+	var out bytes.Buffer
+	stdin := strings.NewReader("www.youtube.com\n")
+	if err := runMatch([]string{"-i", "example/zkeen-site.dat"}, stdin, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "YOUTUBE") {
+		t.Errorf("unexpected output: %q", out.String())
+	}
+}
+
+func TestRunMatchRejectsNonSite(t *testing.T) {
+	err := runMatch([]string{"--site=false", "-i", "example/zkeen-ip.dat", "example.org"}, nil, &bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), "geosite") {
+		t.Errorf("expected geosite-only error, got %v", err)
+	}
+}
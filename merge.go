@@ -0,0 +1,114 @@
+// merge.go
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Conflict policies for tags present in more than one input.
+const (
+	mergeUnion       = "union"
+	mergePreferFirst = "prefer-first"
+	mergePreferLast  = "prefer-last"
+	mergeError       = "error"
+)
+
+// stringList is a flag.Value that collects every occurrence of a repeated flag.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+// mergePolicy is a default conflict policy with optional per-tag overrides.
+type mergePolicy struct {
+	fallback string
+	perTag   map[string]string
+}
+
+func isValidMergePolicy(p string) bool {
+	return p == mergeUnion || p == mergePreferFirst || p == mergePreferLast || p == mergeError
+}
+
+// parseMergePolicy parses "POLICY[,TAG=POLICY...]", e.g. "union,google=prefer-last".
+// Tag names are case-insensitive.
+func parseMergePolicy(s string) (*mergePolicy, error) {
+	p := &mergePolicy{fallback: mergeUnion, perTag: make(map[string]string)}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		tag, policy, hasTag := strings.Cut(part, "=")
+		if !hasTag {
+			policy = tag
+		}
+		if !isValidMergePolicy(policy) {
+			return nil, fmt.Errorf("unknown merge policy %q (use union, prefer-first, prefer-last or error)", policy)
+		}
+		if hasTag {
+			p.perTag[strings.ToLower(tag)] = policy
+		} else {
+			p.fallback = policy
+		}
+	}
+	return p, nil
+}
+
+func (p *mergePolicy) forTag(tag string) string {
+	if policy, ok := p.perTag[strings.ToLower(tag)]; ok {
+		return policy
+	}
+	return p.fallback
+}
+
+// mergeData merges decoded inputs in order. Tags are matched case-insensitively and
+// keep the spelling of the first input that defines them; values of tags defined in
+// several inputs are combined according to the tag's policy.
+func mergeData(names []string, inputs []map[string][]string, policy *mergePolicy) (map[string][]string, error) {
+	result := make(map[string][]string)
+	keys := make(map[string]string)
+	origin := make(map[string]string)
+	for i, input := range inputs {
+		for tag, values := range input {
+			lower := strings.ToLower(tag)
+			key, exists := keys[lower]
+			if !exists {
+				keys[lower] = tag
+				origin[lower] = names[i]
+				result[tag] = values
+				continue
+			}
+			switch policy.forTag(tag) {
+			case mergeUnion:
+				result[key] = uniqueStrings(append(append([]string{}, result[key]...), values...))
+			case mergePreferLast:
+				result[key] = values
+			case mergeError:
+				return nil, fmt.Errorf("tag '%s' is defined in both %s and %s", tag, origin[lower], names[i])
+			}
+		}
+	}
+	return result, nil
+}
+
+// uniqueTags drops tags that match an earlier one case-insensitively, keeping the
+// first spelling like mergeData does.
+func uniqueTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	result := tags[:0]
+	for _, tag := range tags {
+		lower := strings.ToLower(tag)
+		if !seen[lower] {
+			seen[lower] = true
+			result = append(result, tag)
+		}
+	}
+	return result
+}
@@ -0,0 +1,62 @@
+// merge_test.go
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeData(t *testing.T) {
+	inputs := []map[string][]string{
+		{"GOOGLE": {"domain:google.com"}, "CN": {"domain:cn"}},
+		{"google": {"domain:google.com", "full:www.google.cn"}, "EXTRA": {"full:a.b"}},
+	}
+	names := []string{"a.dat", "b.dat"}
+
+	tests := []struct {
+		policy   string
+		expected []string
+	}{
+		{"union", []string{"domain:google.com", "full:www.google.cn"}},
+		{"prefer-first", []string{"domain:google.com"}},
+		{"prefer-last", []string{"domain:google.com", "full:www.google.cn"}},
+		{"error,google=prefer-first", []string{"domain:google.com"}},
+	}
+	for _, tt := range tests {
+		policy, err := parseMergePolicy(tt.policy)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.policy, err)
+		}
+		merged, err := mergeData(names, inputs, policy)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.policy, err)
+		}
+		if len(merged) != 3 || !reflect.DeepEqual(merged["GOOGLE"], tt.expected) {
+			t.Errorf("%s: unexpected result: %v", tt.policy, merged)
+		}
+	}
+}
+
+func TestMergeDataConflictError(t *testing.T) {
+	policy, err := parseMergePolicy("error")
+	if err != nil {
+		t.Fatal(err)
+	}
+	inputs := []map[string][]string{{"US": {"1.0.0.0/8"}}, {"US": {"2.0.0.0/8"}}}
+	if _, err := mergeData([]string{"a", "b"}, inputs, policy); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestParseMergePolicyInvalid(t *testing.T) {
+	if _, err := parseMergePolicy("intersect"); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestUniqueTags(t *testing.T) {
+	got := uniqueTags([]string{"GOOGLE", "CN", "google", "!CN", "!cn", "extra"})
+	if want := []string{"GOOGLE", "CN", "!CN", "extra"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
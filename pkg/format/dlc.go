@@ -0,0 +1,79 @@
+// pkg/format/dlc.go
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Viktor45/dat2json/internal/geodata/router"
+	"github.com/Viktor45/dat2json/internal/geosite"
+)
+
+// SerializeDLC converts geosite rules to a data file of v2fly/domain-list-community,
+// one rule per line in key order: "domain:" rules as bare names, "full:", "regexp:"
+// and "keyword:" rules with their prefix, and attributes as trailing "@attr" tokens.
+// The upstream syntax only has boolean attributes and ends a line at whitespace or
+// "#", so other attribute values and rules containing those characters are errors.
+func SerializeDLC(data map[string][]string, isGeoSite bool) ([]byte, error) {
+	if !isGeoSite {
+		return nil, fmt.Errorf("domain-list-community files hold geosite rules only")
+	}
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b bytes.Buffer
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		for _, s := range data[key] {
+			line, err := dlcLine(s)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+			if seen[line] {
+				continue
+			}
+			seen[line] = true
+			b.WriteString(line)
+			b.WriteByte('\n')
+		}
+	}
+	return b.Bytes(), nil
+}
+
+// dlcLine writes a single rule in domain-list-community syntax.
+func dlcLine(s string) (string, error) {
+	d, err := geosite.ParseRule(s)
+	if err != nil {
+		return "", err
+	}
+	value := d.GetValue()
+	if value == "" || strings.ContainsAny(value, " \t#") {
+		return "", fmt.Errorf("rule %q cannot be written in domain-list-community syntax", s)
+	}
+	var b strings.Builder
+	switch d.GetType() {
+	case router.Domain_Domain:
+	case router.Domain_Full:
+		b.WriteString("full:")
+	case router.Domain_Regex:
+		b.WriteString("regexp:")
+	case router.Domain_Plain:
+		b.WriteString("keyword:")
+	default:
+		return "", fmt.Errorf("rule %q has no domain-list-community equivalent", s)
+	}
+	b.WriteString(value)
+	for _, attr := range d.GetAttribute() {
+		if !attr.GetBoolValue() {
+			return "", fmt.Errorf("rule %q: only boolean attributes such as @%s can be written in domain-list-community syntax", s, attr.GetKey())
+		}
+		b.WriteString(" @")
+		b.WriteString(attr.GetKey())
+	}
+	return b.String(), nil
+}
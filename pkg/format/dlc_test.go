@@ -0,0 +1,41 @@
+// pkg/format/dlc_test.go
+package format
+
+import "testing"
+
+func TestSerializeDLC(t *testing.T) {
+	data := map[string][]string{
+		"google": {
+			"domain:google.com",
+			"full:www.google.cn @cn",
+			"regexp:^g[0-9]+\\.google\\.com$",
+			"keyword:google @ads @cn",
+			"domain:google.com",
+		},
+	}
+	out, err := SerializeDLC(data, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "google.com\nfull:www.google.cn @cn\nregexp:^g[0-9]+\\.google\\.com$\nkeyword:google @ads @cn\n"
+	if string(out) != want {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+}
+
+func TestSerializeDLCInvalid(t *testing.T) {
+	for _, rule := range []string{
+		"domain:example.com @level=2",
+		"domain:example.com @cn=false",
+		"regexp:^a b$",
+		"regexp:^a#b$",
+		"type9:example.com",
+	} {
+		if _, err := SerializeDLC(map[string][]string{"x": {rule}}, true); err == nil {
+			t.Errorf("expected error for %q", rule)
+		}
+	}
+	if _, err := SerializeDLC(map[string][]string{"US": {"1.2.3.0/24"}}, false); err == nil {
+		t.Error("expected error for geoip data")
+	}
+}
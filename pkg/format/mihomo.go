@@ -0,0 +1,196 @@
+// pkg/format/mihomo.go
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Viktor45/dat2json/internal/geodata/router"
+	"github.com/Viktor45/dat2json/internal/geoip"
+	"github.com/Viktor45/dat2json/internal/geosite"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// MRSMagic starts every decompressed Mihomo binary rule-provider (.mrs).
+var MRSMagic = [4]byte{'M', 'R', 'S', 1}
+
+// Rule-provider behaviors as numbered in the .mrs header.
+const (
+	mrsBehaviorDomain = 0
+	mrsBehaviorIPCIDR = 1
+)
+
+// MihomoPayload converts geosite rules or geoip CIDRs to the payload of a Mihomo
+// rule-provider with behavior "domain" or "ipcidr", merging all entries in key order.
+// "full:" rules become bare names and "domain:" rules "+." wildcards; "keyword:" and
+// "regexp:" rules need behavior "classical" and are returned in classical instead.
+// Attributes are dropped. Reverse-match geoip entries ("!CN") cannot be expressed.
+func MihomoPayload(data map[string][]string, isGeoSite bool) (payload, classical []string, err error) {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		values := data[key]
+		if !isGeoSite {
+			if strings.HasPrefix(key, geoip.ReverseMatchPrefix) && len(values) > 0 {
+				return nil, nil, fmt.Errorf("%s: reverse-match entries have no Mihomo rule-provider equivalent", key)
+			}
+			payload = append(payload, values...)
+			continue
+		}
+		for _, s := range values {
+			d, err := geosite.ParseRule(s)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", key, err)
+			}
+			switch d.GetType() {
+			case router.Domain_Full:
+				payload = append(payload, d.GetValue())
+			case router.Domain_Domain:
+				payload = append(payload, "+."+d.GetValue())
+			default:
+				classical = append(classical, s)
+			}
+		}
+	}
+	return uniqueValues(payload), classical, nil
+}
+
+// SerializeMihomoYAML converts geosite rules or geoip CIDRs to a YAML rule-provider
+// with a "payload:" list as described by MihomoPayload.
+func SerializeMihomoYAML(data map[string][]string, isGeoSite bool) ([]byte, error) {
+	payload, _, err := MihomoPayload(data, isGeoSite)
+	if err != nil {
+		return nil, err
+	}
+	if payload == nil {
+		payload = []string{}
+	}
+	return Marshal(map[string][]string{"payload": payload}, "yaml")
+}
+
+// SerializeMihomoText converts geosite rules or geoip CIDRs to a text rule-provider
+// with one payload item per line as described by MihomoPayload.
+func SerializeMihomoText(data map[string][]string, isGeoSite bool) ([]byte, error) {
+	payload, _, err := MihomoPayload(data, isGeoSite)
+	if err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	for _, p := range payload {
+		b.WriteString(p)
+		b.WriteByte('\n')
+	}
+	return b.Bytes(), nil
+}
+
+// SerializeMRS converts geosite rules or geoip CIDRs to a Mihomo binary rule-provider
+// (.mrs) holding the payload produced by MihomoPayload: a zstd stream with the magic,
+// the behavior, the payload count, an empty extra block and the domain or IP set.
+func SerializeMRS(data map[string][]string, isGeoSite bool) ([]byte, error) {
+	payload, _, err := MihomoPayload(data, isGeoSite)
+	if err != nil {
+		return nil, err
+	}
+	if len(payload) == 0 {
+		return nil, fmt.Errorf("no rules left for a binary rule-provider")
+	}
+
+	var body bytes.Buffer
+	w := &binWriter{w: &body}
+	w.bytes(MRSMagic[:])
+	if isGeoSite {
+		w.byte(mrsBehaviorDomain)
+	} else {
+		w.byte(mrsBehaviorIPCIDR)
+	}
+	w.uint64(uint64(len(payload)))
+	w.uint64(0) // extra length
+	if isGeoSite {
+		keys, err := mrsDomainKeys(payload)
+		if err != nil {
+			return nil, err
+		}
+		writeDomainSet(w, newSuccinctSet(keys))
+	} else {
+		set, err := buildIPSet(payload)
+		if err != nil {
+			return nil, err
+		}
+		w.byte(1) // version
+		ranges := set.Ranges()
+		w.uint64(uint64(len(ranges)))
+		for _, r := range ranges {
+			from, to := r.From().As16(), r.To().As16()
+			w.bytes(from[:])
+			w.bytes(to[:])
+		}
+	}
+	if w.err != nil {
+		return nil, w.err
+	}
+
+	var out bytes.Buffer
+	zw, err := zstd.NewWriter(&out, zstd.WithEncoderLevel(zstd.SpeedBestCompression))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := zw.Write(body.Bytes()); err != nil {
+		_ = zw.Close()
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// mrsDomainKeys returns the sorted, reversed keys of Mihomo's domain set. Like Mihomo's
+// domain trie, a "+." wildcard is stored as the domain itself plus the wildcard, and
+// names are lower-cased.
+func mrsDomainKeys(payload []string) ([]string, error) {
+	seen := make(map[string]bool, 2*len(payload))
+	keys := make([]string, 0, 2*len(payload))
+	add := func(d string) {
+		if !seen[d] {
+			seen[d] = true
+			keys = append(keys, reverseDomain(d))
+		}
+	}
+	for _, p := range payload {
+		d := strings.ToLower(p)
+		base := strings.TrimPrefix(d, "+.")
+		if base == "" || strings.HasSuffix(base, ".") || strings.Contains(base, "..") ||
+			strings.ContainsAny(base, "/ \t") {
+			return nil, fmt.Errorf("invalid domain %q", p)
+		}
+		add(base)
+		if base != d {
+			add(d)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// writeDomainSet writes a succinct set in Mihomo's layout: a version byte and the
+// leaves, label bitmap and labels, each preceded by a fixed-size length.
+func writeDomainSet(w *binWriter, ss *succinctSet) {
+	w.byte(1) // version
+	w.uint64(uint64(len(ss.leaves)))
+	for _, word := range ss.leaves {
+		w.uint64(word)
+	}
+	w.uint64(uint64(len(ss.labelBitmap)))
+	for _, word := range ss.labelBitmap {
+		w.uint64(word)
+	}
+	w.uint64(uint64(len(ss.labels)))
+	w.bytes(ss.labels)
+}
@@ -0,0 +1,135 @@
+// pkg/format/mihomo_test.go
+package format
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// mrsBody returns the decompressed content of a binary rule-provider.
+func mrsBody(t *testing.T, data []byte) []byte {
+	t.Helper()
+	zr, err := zstd.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer zr.Close()
+	body, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return body
+}
+
+func TestMihomoPayloadGeoSite(t *testing.T) {
+	data := map[string][]string{
+		"b": {"domain:google.com", "full:www.google.com @cn", "full:www.google.com"},
+		"a": {"keyword:google", "regexp:^g\\.com$", "domain:google.com"},
+	}
+	payload, classical, err := MihomoPayload(data, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"+.google.com", "www.google.com"}; !reflect.DeepEqual(payload, want) {
+		t.Errorf("unexpected payload: %q", payload)
+	}
+	if want := []string{"keyword:google", "regexp:^g\\.com$"}; !reflect.DeepEqual(classical, want) {
+		t.Errorf("unexpected classical rules: %q", classical)
+	}
+}
+
+func TestMihomoPayloadGeoIP(t *testing.T) {
+	payload, _, err := MihomoPayload(map[string][]string{"US": {"8.8.8.0/24"}, "CN": {"1.0.1.0/24"}}, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"1.0.1.0/24", "8.8.8.0/24"}; !reflect.DeepEqual(payload, want) {
+		t.Errorf("unexpected payload: %q", payload)
+	}
+	if _, _, err := MihomoPayload(map[string][]string{"!CN": {"1.0.1.0/24"}}, false); err == nil {
+		t.Error("expected error for reverse-match entry")
+	}
+}
+
+func TestSerializeMihomoYAMLAndText(t *testing.T) {
+	data := map[string][]string{"x": {"domain:a.com", "full:b.com", "keyword:c"}}
+	out, err := SerializeMihomoYAML(data, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "payload:\n  - +.a.com\n  - b.com"; string(out) != want {
+		t.Errorf("unexpected YAML:\n%s", out)
+	}
+	out, err = SerializeMihomoText(data, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "+.a.com\nb.com\n"; string(out) != want {
+		t.Errorf("unexpected text:\n%s", out)
+	}
+	out, err = SerializeMihomoYAML(map[string][]string{"x": {"keyword:c"}}, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "payload: []"; string(out) != want {
+		t.Errorf("unexpected YAML:\n%s", out)
+	}
+}
+
+func TestSerializeMRSGeoSite(t *testing.T) {
+	out, err := SerializeMRS(map[string][]string{"x": {"domain:A", "keyword:b"}}, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// "+.a" is stored as the keys "a" and "a.+":
+	// a trie root -a-> leaf node 1 -.-> node 2 -+-> leaf node 3.
+	want := []byte{
+		'M', 'R', 'S', 1,
+		0x00,                   // behavior domain
+		0, 0, 0, 0, 0, 0, 0, 1, // rule count
+		0, 0, 0, 0, 0, 0, 0, 0, // extra length
+		0x01,                                              // domain set version
+		0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0x0a, // leaves: nodes 1 and 3
+		0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0x6a, // label bitmap: 0 1 0 1 0 1 1
+		0, 0, 0, 0, 0, 0, 0, 3, 'a', '.', '+', // labels
+	}
+	if got := mrsBody(t, out); !bytes.Equal(got, want) {
+		t.Errorf("unexpected body:\n got %x\nwant %x", got, want)
+	}
+}
+
+func TestSerializeMRSGeoIP(t *testing.T) {
+	out, err := SerializeMRS(map[string][]string{"US": {"1.2.3.0/25", "1.2.3.128/25"}}, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []byte{
+		'M', 'R', 'S', 1,
+		0x01,                   // behavior ipcidr
+		0, 0, 0, 0, 0, 0, 0, 2, // rule count
+		0, 0, 0, 0, 0, 0, 0, 0, // extra length
+		0x01,                   // IP set version
+		0, 0, 0, 0, 0, 0, 0, 1, // range count
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xff, 1, 2, 3, 0,
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xff, 1, 2, 3, 255,
+	}
+	if got := mrsBody(t, out); !bytes.Equal(got, want) {
+		t.Errorf("unexpected body:\n got %x\nwant %x", got, want)
+	}
+}
+
+func TestSerializeMRSInvalid(t *testing.T) {
+	if _, err := SerializeMRS(map[string][]string{"x": {"keyword:b"}}, true); err == nil {
+		t.Error("expected error for empty payload")
+	}
+	if _, err := SerializeMRS(map[string][]string{"x": {"full:a..com"}}, true); err == nil {
+		t.Error("expected error for invalid domain")
+	}
+	if _, err := SerializeMRS(map[string][]string{"US": {"1.2.3.4/33"}}, false); err == nil {
+		t.Error("expected error for invalid CIDR")
+	}
+}
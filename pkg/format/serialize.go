@@ -11,14 +11,20 @@ import (
 
 // Serialize converts a map of strings to JSON or YAML bytes based on the specified format.
 func Serialize(data map[string][]string, format string) ([]byte, error) {
+	return Marshal(data, format)
+}
+
+// Marshal converts any JSON/YAML-encodable value to bytes in the specified format,
+// using the same layout as Serialize.
+func Marshal(v any, format string) ([]byte, error) {
 	switch format {
 	case "json":
-		return json.MarshalIndent(data, "", "  ")
+		return json.MarshalIndent(v, "", "  ")
 	case "yaml":
 		buf := &bytes.Buffer{}
 		enc := yaml.NewEncoder(buf)
 		enc.SetIndent(2)
-		if err := enc.Encode(data); err != nil {
+		if err := enc.Encode(v); err != nil {
 			return nil, err
 		}
 		out := buf.Bytes()
@@ -30,3 +36,21 @@ func Serialize(data map[string][]string, format string) ([]byte, error) {
 		return nil, fmt.Errorf("unsupported format: %s", format)
 	}
 }
+
+// Deserialize parses JSON or YAML bytes produced by Serialize back into a map of strings.
+func Deserialize(data []byte, format string) (map[string][]string, error) {
+	result := make(map[string][]string)
+	switch format {
+	case "json":
+		if err := json.Unmarshal(data, &result); err != nil {
+			return nil, err
+		}
+	case "yaml":
+		if err := yaml.Unmarshal(data, &result); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported format: %s", format)
+	}
+	return result, nil
+}
@@ -34,3 +34,40 @@ func TestSerializeUnknown(t *testing.T) {
 		t.Fatal("expected error")
 	}
 }
+
+func TestDeserializeRoundTrip(t *testing.T) {
+	data := map[string][]string{"test": {"a", "b"}}
+	for _, f := range []string{"json", "yaml"} {
+		out, err := Serialize(data, f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		back, err := Deserialize(out, f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(back["test"]) != 2 || back["test"][1] != "b" {
+			t.Errorf("%s: unexpected result: %v", f, back)
+		}
+	}
+}
+
+func TestDeserializeUnknown(t *testing.T) {
+	_, err := Deserialize(nil, "xml")
+	if err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestMarshalStruct(t *testing.T) {
+	v := struct {
+		Added []string `json:"added" yaml:"added"`
+	}{Added: []string{"a"}}
+	out, err := Marshal(v, "yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "added:\n  - a" {
+		t.Errorf("unexpected YAML: %q", out)
+	}
+}
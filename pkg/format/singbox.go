@@ -0,0 +1,116 @@
+// pkg/format/singbox.go
+package format
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Viktor45/dat2json/internal/geodata/router"
+	"github.com/Viktor45/dat2json/internal/geosite"
+)
+
+// SingBoxVersion is the sing-box source rule-set version written by SerializeSingBox.
+const SingBoxVersion = 2
+
+// SingBoxRuleSet is a sing-box source rule-set.
+type SingBoxRuleSet struct {
+	Version int           `json:"version"`
+	Rules   []SingBoxRule `json:"rules"`
+}
+
+// SingBoxRule is a headless sing-box rule. It matches when any of its items match,
+// or when none match if Invert is set.
+type SingBoxRule struct {
+	Domain        []string `json:"domain,omitempty"`
+	DomainSuffix  []string `json:"domain_suffix,omitempty"`
+	DomainKeyword []string `json:"domain_keyword,omitempty"`
+	DomainRegex   []string `json:"domain_regex,omitempty"`
+	IPCIDR        []string `json:"ip_cidr,omitempty"`
+	Invert        bool     `json:"invert,omitempty"`
+}
+
+// SingBoxRules converts geosite rules or geoip CIDRs to a sing-box rule-set with one
+// rule per non-empty entry, in key order. "full:" rules become domain items,
+// "domain:" rules domain_suffix items, "keyword:" rules domain_keyword items and
+// "regexp:" rules domain_regex items; attributes are dropped. Reverse-match geoip
+// entries ("!CN") become inverted rules.
+func SingBoxRules(data map[string][]string, isGeoSite bool) (*SingBoxRuleSet, error) {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	rs := &SingBoxRuleSet{Version: SingBoxVersion, Rules: []SingBoxRule{}}
+	for _, key := range keys {
+		values := data[key]
+		if len(values) == 0 {
+			continue
+		}
+		var rule SingBoxRule
+		if isGeoSite {
+			if err := addSingBoxDomains(&rule, values); err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+		} else {
+			rule.IPCIDR = uniqueValues(values)
+			rule.Invert = strings.HasPrefix(key, "!")
+		}
+		rs.Rules = append(rs.Rules, rule)
+	}
+	return rs, nil
+}
+
+func addSingBoxDomains(rule *SingBoxRule, rules []string) error {
+	for _, s := range rules {
+		d, err := geosite.ParseRule(s)
+		if err != nil {
+			return err
+		}
+		switch d.GetType() {
+		case router.Domain_Full:
+			rule.Domain = append(rule.Domain, d.GetValue())
+		case router.Domain_Domain:
+			rule.DomainSuffix = append(rule.DomainSuffix, d.GetValue())
+		case router.Domain_Plain:
+			rule.DomainKeyword = append(rule.DomainKeyword, d.GetValue())
+		case router.Domain_Regex:
+			rule.DomainRegex = append(rule.DomainRegex, d.GetValue())
+		default:
+			return fmt.Errorf("rule %q has no sing-box equivalent", s)
+		}
+	}
+	rule.Domain = uniqueValues(rule.Domain)
+	rule.DomainSuffix = uniqueValues(rule.DomainSuffix)
+	rule.DomainKeyword = uniqueValues(rule.DomainKeyword)
+	rule.DomainRegex = uniqueValues(rule.DomainRegex)
+	return nil
+}
+
+// uniqueValues drops repeated values, which appear when rules differ only in attributes.
+func uniqueValues(values []string) []string {
+	if len(values) == 0 {
+		return nil
+	}
+	seen := make(map[string]bool, len(values))
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// SerializeSingBox converts geosite rules or geoip CIDRs to an indented sing-box
+// source rule-set as described by SingBoxRules.
+func SerializeSingBox(data map[string][]string, isGeoSite bool) ([]byte, error) {
+	rs, err := SingBoxRules(data, isGeoSite)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(rs, "", "  ")
+}
@@ -0,0 +1,56 @@
+// pkg/format/singbox_test.go
+package format
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestSingBoxRulesGeoSite(t *testing.T) {
+	data := map[string][]string{
+		"google": {"domain:google.com", "full:www.google.com @cn", "full:www.google.com", "keyword:google", "regexp:^g[0-9]+\\.google\\.com$"},
+		"empty":  {},
+	}
+	rs, err := SingBoxRules(data, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []SingBoxRule{{
+		Domain:        []string{"www.google.com"},
+		DomainSuffix:  []string{"google.com"},
+		DomainKeyword: []string{"google"},
+		DomainRegex:   []string{"^g[0-9]+\\.google\\.com$"},
+	}}
+	if rs.Version != SingBoxVersion || !reflect.DeepEqual(rs.Rules, want) {
+		t.Errorf("unexpected rule-set: %+v", rs)
+	}
+}
+
+func TestSingBoxRulesGeoIP(t *testing.T) {
+	data := map[string][]string{"US": {"8.8.8.0/24", "2001:4860::/32"}, "!CN": {"1.0.1.0/24"}}
+	out, err := SerializeSingBox(data, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var rs SingBoxRuleSet
+	if err := json.Unmarshal(out, &rs); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []SingBoxRule{
+		{IPCIDR: []string{"1.0.1.0/24"}, Invert: true},
+		{IPCIDR: []string{"8.8.8.0/24", "2001:4860::/32"}},
+	}
+	if !reflect.DeepEqual(rs.Rules, want) {
+		t.Errorf("unexpected rules: %+v", rs.Rules)
+	}
+}
+
+func TestSingBoxRulesInvalid(t *testing.T) {
+	if _, err := SingBoxRules(map[string][]string{"x": {"type9:example.com"}}, true); err == nil {
+		t.Error("expected error for rule without sing-box equivalent")
+	}
+	if _, err := SingBoxRules(map[string][]string{"x": {"example.com"}}, true); err == nil {
+		t.Error("expected error for rule without type prefix")
+	}
+}
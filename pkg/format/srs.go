@@ -0,0 +1,277 @@
+// pkg/format/srs.go
+package format
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/netip"
+	"sort"
+	"unicode/utf8"
+
+	"go4.org/netipx"
+)
+
+// SRSMagic starts every sing-box binary rule-set.
+var SRSMagic = [3]byte{'S', 'R', 'S'}
+
+// Item types of a default rule in a binary rule-set, as numbered by sing-box.
+const (
+	srsItemDomain        = 2
+	srsItemDomainKeyword = 3
+	srsItemDomainRegex   = 4
+	srsItemIPCIDR        = 6
+	srsItemFinal         = 0xFF
+)
+
+// Labels that terminate a reversed domain in the succinct set: srsRootLabel marks a
+// suffix that matches the domain itself and its subdomains, srsPrefixLabel a suffix
+// written with a leading dot that matches subdomains only.
+const (
+	srsPrefixLabel = '\r'
+	srsRootLabel   = '\n'
+)
+
+// SerializeSRS converts geosite rules or geoip CIDRs to a sing-box binary rule-set
+// (.srs) holding the rules produced by SingBoxRules.
+func SerializeSRS(data map[string][]string, isGeoSite bool) ([]byte, error) {
+	rs, err := SingBoxRules(data, isGeoSite)
+	if err != nil {
+		return nil, err
+	}
+	return EncodeSRS(rs)
+}
+
+// EncodeSRS writes a rule-set in the sing-box binary layout: the magic, a version
+// byte and a zlib-compressed body with the rule count and every rule's items.
+func EncodeSRS(rs *SingBoxRuleSet) ([]byte, error) {
+	var out bytes.Buffer
+	out.Write(SRSMagic[:])
+	out.WriteByte(byte(rs.Version))
+	zw, err := zlib.NewWriterLevel(&out, zlib.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	bw := bufio.NewWriter(zw)
+	w := &binWriter{w: bw}
+	w.uvarint(uint64(len(rs.Rules)))
+	for i, rule := range rs.Rules {
+		if err := writeSRSRule(w, rule, rs.Version); err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i, err)
+		}
+	}
+	if w.err != nil {
+		return nil, w.err
+	}
+	if err := bw.Flush(); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// binWriter writes binary fields to w and keeps the first write error, which the
+// caller checks once after writing a whole structure.
+type binWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (w *binWriter) bytes(b []byte) {
+	if w.err == nil {
+		_, w.err = w.w.Write(b)
+	}
+}
+
+func (w *binWriter) byte(b byte) {
+	w.bytes([]byte{b})
+}
+
+func (w *binWriter) uint64(v uint64) {
+	w.bytes(binary.BigEndian.AppendUint64(nil, v))
+}
+
+func (w *binWriter) uvarint(v uint64) {
+	w.bytes(binary.AppendUvarint(nil, v))
+}
+
+// writeSRSRule writes a default rule.
+func writeSRSRule(w *binWriter, rule SingBoxRule, version int) error {
+	w.byte(0) // default rule
+	if len(rule.Domain) > 0 || len(rule.DomainSuffix) > 0 {
+		w.byte(srsItemDomain)
+		writeSuccinctSet(w, newSuccinctSet(srsDomainKeys(rule.Domain, rule.DomainSuffix, version == 1)))
+	}
+	if len(rule.DomainKeyword) > 0 {
+		w.byte(srsItemDomainKeyword)
+		writeStrings(w, rule.DomainKeyword)
+	}
+	if len(rule.DomainRegex) > 0 {
+		w.byte(srsItemDomainRegex)
+		writeStrings(w, rule.DomainRegex)
+	}
+	if len(rule.IPCIDR) > 0 {
+		set, err := buildIPSet(rule.IPCIDR)
+		if err != nil {
+			return err
+		}
+		w.byte(srsItemIPCIDR)
+		writeIPSet(w, set)
+	}
+	w.byte(srsItemFinal)
+	if rule.Invert {
+		w.byte(1)
+	} else {
+		w.byte(0)
+	}
+	return w.err
+}
+
+// srsDomainKeys returns the sorted, reversed keys of the domain matcher. Version 1
+// rule-sets store a suffix as the domain itself plus "." + domain.
+func srsDomainKeys(domains, suffixes []string, legacy bool) []string {
+	keys := make([]string, 0, len(domains)+2*len(suffixes))
+	seen := make(map[string]bool, cap(keys))
+	for _, d := range suffixes {
+		if d == "" || seen[d] {
+			continue
+		}
+		seen[d] = true
+		switch {
+		case d[0] == '.':
+			keys = append(keys, reverseDomain(string(srsPrefixLabel)+d))
+		case legacy:
+			keys = append(keys, reverseDomain(d))
+			if dotted := "." + d; !seen[dotted] {
+				seen[dotted] = true
+				keys = append(keys, reverseDomain(string(srsPrefixLabel)+dotted))
+			}
+		default:
+			keys = append(keys, reverseDomain(string(srsRootLabel)+d))
+		}
+	}
+	for _, d := range domains {
+		if d == "" || seen[d] {
+			continue
+		}
+		seen[d] = true
+		keys = append(keys, reverseDomain(d))
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// reverseDomain reverses a domain rune by rune, so that sorted keys share suffixes.
+func reverseDomain(domain string) string {
+	n := len(domain)
+	b := make([]byte, n)
+	for i := 0; i < n; {
+		r, size := utf8.DecodeRuneInString(domain[i:])
+		i += size
+		utf8.EncodeRune(b[n-i:], r)
+	}
+	return string(b)
+}
+
+// succinctSet is a LOUDS-encoded trie of sorted keys as read by sing-box: labels holds
+// the edge labels in breadth-first order, labelBitmap has a 0 per edge and a 1 closing
+// each node, and leaves marks the nodes where a key ends.
+type succinctSet struct {
+	leaves, labelBitmap []uint64
+	labels              []byte
+}
+
+func newSuccinctSet(keys []string) *succinctSet {
+	ss := &succinctSet{}
+	type node struct{ start, end, col int }
+	queue := []node{{0, len(keys), 0}}
+	bit := 0
+	for i := 0; i < len(queue); i++ {
+		n := queue[i]
+		if n.start < n.end && n.col == len(keys[n.start]) {
+			n.start++
+			setBit(&ss.leaves, i)
+		}
+		for j := n.start; j < n.end; {
+			from := j
+			for j < n.end && keys[j][n.col] == keys[from][n.col] {
+				j++
+			}
+			queue = append(queue, node{from, j, n.col + 1})
+			ss.labels = append(ss.labels, keys[from][n.col])
+			growBits(&ss.labelBitmap, bit)
+			bit++
+		}
+		setBit(&ss.labelBitmap, bit)
+		bit++
+	}
+	return ss
+}
+
+func growBits(bm *[]uint64, i int) {
+	for i>>6 >= len(*bm) {
+		*bm = append(*bm, 0)
+	}
+}
+
+func setBit(bm *[]uint64, i int) {
+	growBits(bm, i)
+	(*bm)[i>>6] |= 1 << uint(i&63)
+}
+
+func writeSuccinctSet(w *binWriter, ss *succinctSet) {
+	w.byte(0) // reserved
+	writeWords(w, ss.leaves)
+	writeWords(w, ss.labelBitmap)
+	writeBytes(w, ss.labels)
+}
+
+func buildIPSet(cidrs []string) (*netipx.IPSet, error) {
+	var b netipx.IPSetBuilder
+	for _, s := range cidrs {
+		if p, err := netip.ParsePrefix(s); err == nil {
+			b.AddPrefix(p)
+		} else if addr, addrErr := netip.ParseAddr(s); addrErr == nil {
+			b.Add(addr)
+		} else {
+			return nil, fmt.Errorf("invalid CIDR %q: %w", s, err)
+		}
+	}
+	return b.IPSet()
+}
+
+// writeIPSet writes the address ranges of set with a format version of 1 and a
+// fixed-size range count, as sing-box expects.
+func writeIPSet(w *binWriter, set *netipx.IPSet) {
+	ranges := set.Ranges()
+	w.byte(1)
+	w.uint64(uint64(len(ranges)))
+	for _, r := range ranges {
+		writeBytes(w, r.From().AsSlice())
+		writeBytes(w, r.To().AsSlice())
+	}
+}
+
+func writeWords(w *binWriter, words []uint64) {
+	w.uvarint(uint64(len(words)))
+	for _, word := range words {
+		w.uint64(word)
+	}
+}
+
+func writeStrings(w *binWriter, values []string) {
+	w.uvarint(uint64(len(values)))
+	for _, v := range values {
+		writeBytes(w, []byte(v))
+	}
+}
+
+func writeBytes(w *binWriter, b []byte) {
+	w.uvarint(uint64(len(b)))
+	w.bytes(b)
+}
@@ -0,0 +1,89 @@
+// pkg/format/srs_test.go
+package format
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"testing"
+)
+
+// srsBody checks the header of a binary rule-set and returns its decompressed body.
+func srsBody(t *testing.T, data []byte) []byte {
+	t.Helper()
+	if !bytes.HasPrefix(data, []byte("SRS\x02")) {
+		t.Fatalf("unexpected header: %q", data[:min(len(data), 4)])
+	}
+	zr, err := zlib.NewReader(bytes.NewReader(data[4:]))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	body, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return body
+}
+
+func TestSerializeSRSGeoSite(t *testing.T) {
+	data := map[string][]string{"x": {"domain:a", "keyword:b", "regexp:^c$"}}
+	out, err := SerializeSRS(data, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The suffix "a" is stored reversed with a trailing root label as the key "a\n":
+	// a trie root -a-> node 1 -\n-> leaf node 2.
+	want := []byte{
+		0x01,       // rule count
+		0x00,       // default rule
+		0x02, 0x00, // domain item, reserved byte
+		0x01, 0, 0, 0, 0, 0, 0, 0, 0x04, // leaves: node 2
+		0x01, 0, 0, 0, 0, 0, 0, 0, 0x1a, // label bitmap: 0 1 0 1 1
+		0x02, 'a', '\n', // labels
+		0x03, 0x01, 0x01, 'b', // keywords
+		0x04, 0x01, 0x03, '^', 'c', '$', // regexps
+		0xff, 0x00, // end of items, not inverted
+	}
+	if got := srsBody(t, out); !bytes.Equal(got, want) {
+		t.Errorf("unexpected body:\n got %x\nwant %x", got, want)
+	}
+}
+
+func TestSerializeSRSGeoIP(t *testing.T) {
+	data := map[string][]string{"!CN": {"1.2.3.0/25", "1.2.3.128/25", "::1"}}
+	out, err := SerializeSRS(data, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []byte{
+		0x01, 0x00,
+		0x06, 0x01, // IP CIDR item, IP set version
+		0, 0, 0, 0, 0, 0, 0, 0x02, // range count
+		0x04, 1, 2, 3, 0, 0x04, 1, 2, 3, 255,
+		0x10, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1,
+		0x10, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1,
+		0xff, 0x01, // inverted
+	}
+	if got := srsBody(t, out); !bytes.Equal(got, want) {
+		t.Errorf("unexpected body:\n got %x\nwant %x", got, want)
+	}
+}
+
+func TestSRSDomainKeysLegacy(t *testing.T) {
+	keys := srsDomainKeys([]string{"www.example.com", "example.com"}, []string{"example.com"}, true)
+	want := []string{"moc.elpmaxe", "moc.elpmaxe.\r", "moc.elpmaxe.www"}
+	if len(keys) != len(want) {
+		t.Fatalf("unexpected keys: %q", keys)
+	}
+	for i := range want {
+		if keys[i] != want[i] {
+			t.Errorf("key %d: got %q, want %q", i, keys[i], want[i])
+		}
+	}
+}
+
+func TestSerializeSRSInvalidCIDR(t *testing.T) {
+	if _, err := SerializeSRS(map[string][]string{"US": {"1.2.3.4/33"}}, false); err == nil {
+		t.Error("expected error for invalid CIDR")
+	}
+}
@@ -0,0 +1,154 @@
+// pkg/geodata/decode.go
+package geodata
+
+import (
+	"fmt"
+	"io"
+	"iter"
+	"net/netip"
+	"sort"
+	"strings"
+
+	"github.com/Viktor45/dat2json/internal/geodata/router"
+	"github.com/Viktor45/dat2json/internal/geoip"
+	"github.com/Viktor45/dat2json/internal/geosite"
+)
+
+// DecodeGeoIP decodes a binary or Protobuf geoip.dat file into typed entries.
+func DecodeGeoIP(data []byte) (*GeoIPList, error) {
+	decoded, err := geoip.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	return newGeoIPList(decoded)
+}
+
+// DecodeGeoSite decodes a binary or Protobuf geosite.dat file into typed entries.
+func DecodeGeoSite(data []byte) (*GeoSiteList, error) {
+	decoded, err := geosite.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	return newGeoSiteList(decoded)
+}
+
+// GeoIPEntries returns an iterator that decodes a geoip.dat stream one entry at a time,
+// in file order. Iteration stops after the first non-nil error.
+func GeoIPEntries(r io.Reader) iter.Seq2[GeoIP, error] {
+	return func(yield func(GeoIP, error) bool) {
+		dr := geoip.NewReader(r)
+		for dr.Next() {
+			entry, err := newGeoIP(dr.Entry())
+			if !yield(entry, err) || err != nil {
+				return
+			}
+		}
+		if err := dr.Err(); err != nil {
+			yield(GeoIP{}, err)
+		}
+	}
+}
+
+// GeoSiteEntries returns an iterator that decodes a geosite.dat stream one entry at a time,
+// in file order. Iteration stops after the first non-nil error.
+func GeoSiteEntries(r io.Reader) iter.Seq2[GeoSite, error] {
+	return func(yield func(GeoSite, error) bool) {
+		dr := geosite.NewReader(r)
+		for dr.Next() {
+			entry, err := newGeoSite(dr.Entry())
+			if !yield(entry, err) || err != nil {
+				return
+			}
+		}
+		if err := dr.Err(); err != nil {
+			yield(GeoSite{}, err)
+		}
+	}
+}
+
+func newGeoIP(key string, cidrs []string) (GeoIP, error) {
+	code, reverse := strings.CutPrefix(key, geoip.ReverseMatchPrefix)
+	entry := GeoIP{CountryCode: code, ReverseMatch: reverse, Prefixes: make([]netip.Prefix, 0, len(cidrs))}
+	for _, cidr := range cidrs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return GeoIP{}, fmt.Errorf("country %s: %w", code, err)
+		}
+		entry.Prefixes = append(entry.Prefixes, prefix)
+	}
+	return entry, nil
+}
+
+func newGeoSite(tag string, rules []string) (GeoSite, error) {
+	entry := GeoSite{Tag: tag, Rules: make([]Rule, 0, len(rules))}
+	for _, s := range rules {
+		domain, err := geosite.ParseRule(s)
+		if err != nil {
+			return GeoSite{}, fmt.Errorf("tag %s: %w", tag, err)
+		}
+		entry.Rules = append(entry.Rules, newRule(domain))
+	}
+	return entry, nil
+}
+
+func newGeoIPList(decoded map[string][]string) (*GeoIPList, error) {
+	list := &GeoIPList{Entries: make([]GeoIP, 0, len(decoded))}
+	for key, cidrs := range decoded {
+		entry, err := newGeoIP(key, cidrs)
+		if err != nil {
+			return nil, err
+		}
+		list.Entries = append(list.Entries, entry)
+	}
+	sort.Slice(list.Entries, func(i, j int) bool {
+		a, b := list.Entries[i], list.Entries[j]
+		if a.CountryCode != b.CountryCode {
+			return a.CountryCode < b.CountryCode
+		}
+		return !a.ReverseMatch && b.ReverseMatch
+	})
+	return list, nil
+}
+
+func newGeoSiteList(decoded map[string][]string) (*GeoSiteList, error) {
+	list := &GeoSiteList{Entries: make([]GeoSite, 0, len(decoded))}
+	for tag, rules := range decoded {
+		entry, err := newGeoSite(tag, rules)
+		if err != nil {
+			return nil, err
+		}
+		list.Entries = append(list.Entries, entry)
+	}
+	sort.Slice(list.Entries, func(i, j int) bool {
+		return list.Entries[i].Tag < list.Entries[j].Tag
+	})
+	return list, nil
+}
+
+// newRule converts a Protobuf domain into a typed rule.
+func newRule(d *router.Domain) Rule {
+	rule := Rule{Value: d.GetValue()}
+	switch d.GetType() {
+	case router.Domain_Domain:
+		rule.Type = RuleDomain
+	case router.Domain_Full:
+		rule.Type = RuleFull
+	case router.Domain_Regex:
+		rule.Type = RuleRegexp
+	case router.Domain_Plain:
+		rule.Type = RuleKeyword
+	default:
+		rule.Type = RuleType(d.GetType())
+	}
+	for _, attr := range d.GetAttribute() {
+		a := Attribute{Key: attr.GetKey()}
+		switch v := attr.GetTypedValue().(type) {
+		case *router.Domain_Attribute_BoolValue:
+			a.Value = v.BoolValue
+		case *router.Domain_Attribute_IntValue:
+			a.Value = v.IntValue
+		}
+		rule.Attributes = append(rule.Attributes, a)
+	}
+	return rule
+}
@@ -0,0 +1,146 @@
+// pkg/geodata/decode_test.go
+package geodata
+
+import (
+	"bytes"
+	"net/netip"
+	"os"
+	"testing"
+
+	"github.com/Viktor45/dat2json/internal/geodata/router"
+
+	"google.golang.org/protobuf/proto"
+)
+
+func TestDecodeGeoIP(t *testing.T) {
+	data := []byte("GEOI\x01")
+	data = append(data, 0x02, 'U', 'S')
+	data = append(data, 0x01)
+	data = append(data, 1, 2, 3, 4, 24)
+
+	list, err := DecodeGeoIP(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	entry := list.Find("us")
+	if entry == nil {
+		t.Fatal("expected entry for US")
+	}
+	expected := netip.MustParsePrefix("1.2.3.4/24")
+	if len(entry.Prefixes) != 1 || entry.Prefixes[0] != expected {
+		t.Errorf("expected [%s], got %v", expected, entry.Prefixes)
+	}
+}
+
+func TestDecodeGeoIPExample(t *testing.T) {
+	data, err := os.ReadFile("../../example/zkeen-ip.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, err := DecodeGeoIP(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := 1; i < len(list.Entries); i++ {
+		if list.Entries[i-1].CountryCode >= list.Entries[i].CountryCode {
+			t.Fatalf("entries not sorted at %d", i)
+		}
+	}
+	if list.Find("RU") == nil {
+		t.Error("expected entry for RU")
+	}
+}
+
+func TestDecodeGeoIPReverseMatchOrder(t *testing.T) {
+	data, _ := proto.Marshal(&router.GeoIPList{Entry: []*router.GeoIP{
+		{CountryCode: "CN", ReverseMatch: true, Cidr: []*router.CIDR{{Ip: []byte{1, 0, 1, 0}, Prefix: 24}}},
+		{CountryCode: "CN", Cidr: []*router.CIDR{{Ip: []byte{1, 0, 2, 0}, Prefix: 24}}},
+		{CountryCode: "AU", Cidr: []*router.CIDR{{Ip: []byte{1, 0, 0, 0}, Prefix: 24}}},
+	}})
+
+	for range 20 {
+		list, err := DecodeGeoIP(data)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(list.Entries) != 3 || list.Entries[0].CountryCode != "AU" ||
+			list.Entries[1].ReverseMatch || !list.Entries[2].ReverseMatch {
+			t.Fatalf("unexpected order: %+v", list.Entries)
+		}
+		if entry := list.Find("cn"); entry == nil || entry.ReverseMatch {
+			t.Fatalf("unexpected entry: %+v", entry)
+		}
+	}
+}
+
+func TestDecodeGeoSite(t *testing.T) {
+	geositeList := &router.GeoSiteList{
+		Entry: []*router.GeoSite{
+			{
+				CountryCode: "google",
+				Domain: []*router.Domain{
+					{Type: router.Domain_Full, Value: "www.google.com"},
+					{
+						Type:      router.Domain_Domain,
+						Value:     "google.cn",
+						Attribute: []*router.Domain_Attribute{{Key: "cn", TypedValue: &router.Domain_Attribute_BoolValue{BoolValue: true}}},
+					},
+				},
+			},
+		},
+	}
+	data, _ := proto.Marshal(geositeList)
+
+	list, err := DecodeGeoSite(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	entry := list.Find("GOOGLE")
+	if entry == nil || len(entry.Rules) != 2 {
+		t.Fatalf("unexpected entries: %v", list.Entries)
+	}
+	if entry.Rules[0].Type != RuleFull || entry.Rules[0].Value != "www.google.com" {
+		t.Errorf("unexpected rule: %+v", entry.Rules[0])
+	}
+	if !entry.Rules[1].HasAttribute("cn") || entry.Rules[1].String() != "domain:google.cn @cn" {
+		t.Errorf("unexpected rule: %+v", entry.Rules[1])
+	}
+}
+
+func TestRuleTypeString(t *testing.T) {
+	if RuleKeyword.String() != "keyword" || RuleType(9).String() != "type9" {
+		t.Errorf("unexpected strings: %s %s", RuleKeyword, RuleType(9))
+	}
+}
+
+func TestGeoSiteEntries(t *testing.T) {
+	f, err := os.Open("../../example/zkeen-site.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+
+	count := 0
+	for entry, err := range GeoSiteEntries(f) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if entry.Tag == "" || len(entry.Rules) == 0 {
+			t.Errorf("unexpected entry: %+v", entry)
+		}
+		count++
+	}
+	if count != 6 {
+		t.Errorf("expected 6 entries, got %d", count)
+	}
+}
+
+func TestGeoIPEntriesError(t *testing.T) {
+	var gotErr error
+	for _, err := range GeoIPEntries(bytes.NewReader([]byte("INVALID"))) {
+		gotErr = err
+	}
+	if gotErr == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -0,0 +1,128 @@
+// Package geodata provides typed models and decoders for geoip.dat and geosite.dat files.
+package geodata
+
+import (
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// GeoIP is a single country entry of a geoip.dat file.
+type GeoIP struct {
+	CountryCode  string
+	ReverseMatch bool
+	// Prefixes are stored as in the file; host bits are not masked.
+	Prefixes []netip.Prefix
+}
+
+// GeoIPList is the decoded content of a geoip.dat file, sorted by country code with
+// a reverse-match entry after the regular entry of the same country.
+type GeoIPList struct {
+	Entries []GeoIP
+}
+
+// Find returns the entry with the given country code (case-insensitive), or nil.
+// If the country has both a regular and a reverse-match entry, the regular one is returned.
+func (l *GeoIPList) Find(code string) *GeoIP {
+	for i := range l.Entries {
+		if strings.EqualFold(l.Entries[i].CountryCode, code) {
+			return &l.Entries[i]
+		}
+	}
+	return nil
+}
+
+// RuleType is the matching type of a geosite rule.
+type RuleType int
+
+// Known rule types. Unknown types found in a file keep their numeric value.
+const (
+	RuleDomain RuleType = iota
+	RuleFull
+	RuleRegexp
+	RuleKeyword
+)
+
+// String returns the rule prefix without the trailing colon, e.g. "domain".
+func (t RuleType) String() string {
+	switch t {
+	case RuleDomain:
+		return "domain"
+	case RuleFull:
+		return "full"
+	case RuleRegexp:
+		return "regexp"
+	case RuleKeyword:
+		return "keyword"
+	default:
+		return "type" + strconv.Itoa(int(t))
+	}
+}
+
+// Attribute is a key with an optional typed value attached to a geosite rule.
+type Attribute struct {
+	Key string
+	// Value is a bool, an int64, or nil when the attribute carries no value.
+	Value any
+}
+
+// Rule is a single domain rule of a geosite tag.
+type Rule struct {
+	Type       RuleType
+	Value      string
+	Attributes []Attribute
+}
+
+// String returns the rule in dat2json's text form, e.g. "domain:google.cn @cn".
+func (r Rule) String() string {
+	var b strings.Builder
+	b.WriteString(r.Type.String())
+	b.WriteByte(':')
+	b.WriteString(r.Value)
+	for _, attr := range r.Attributes {
+		b.WriteString(" @")
+		b.WriteString(attr.Key)
+		switch v := attr.Value.(type) {
+		case bool:
+			if !v {
+				b.WriteString("=false")
+			}
+		case int64:
+			b.WriteString("=" + strconv.FormatInt(v, 10))
+		default:
+			b.WriteString("=")
+		}
+	}
+	return b.String()
+}
+
+// HasAttribute reports whether the rule carries an attribute with the given key (case-insensitive).
+func (r Rule) HasAttribute(key string) bool {
+	for _, attr := range r.Attributes {
+		if strings.EqualFold(attr.Key, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// GeoSite is a single tag entry of a geosite.dat file.
+type GeoSite struct {
+	Tag   string
+	Rules []Rule
+}
+
+// GeoSiteList is the decoded content of a geosite.dat file, sorted by tag.
+type GeoSiteList struct {
+	Entries []GeoSite
+}
+
+// Find returns the entry with the given tag (case-insensitive), or nil.
+func (l *GeoSiteList) Find(tag string) *GeoSite {
+	for i := range l.Entries {
+		if strings.EqualFold(l.Entries[i].Tag, tag) {
+			return &l.Entries[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+// validate.go
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/Viktor45/dat2json/internal/geodata"
+	"github.com/Viktor45/dat2json/internal/geoip"
+	"github.com/Viktor45/dat2json/internal/geosite"
+	"github.com/Viktor45/dat2json/pkg/format"
+)
+
+// validateReport is the machine-readable result of "dat2json validate".
+type validateReport struct {
+	Errors   int               `json:"errors" yaml:"errors"`
+	Warnings int               `json:"warnings" yaml:"warnings"`
+	Findings []geodata.Finding `json:"findings" yaml:"findings"`
+}
+
+// runValidate implements "dat2json validate": it decodes an input, reports problems
+// with its tags, rules and CIDRs, and fails when any error (or, with --strict, any
+// warning) was found so CI jobs can gate on it.
+func runValidate(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	input := fs.String("i", "", "Input .dat file (or .json/.yaml/.yml source)")
+	ip := fs.Bool("ip", false, "Treat input as geoip.dat")
+	site := fs.Bool("site", false, "Treat input as geosite.dat")
+	outFormat := fs.String("format", "text", "Output format: text, json or yaml")
+	strict := fs.Bool("strict", false, "Fail on warnings as well as errors")
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(fs.Output(), "Usage: %s validate --ip|--site -i FILE [--format text|json|yaml] [--strict]\n", os.Args[0])
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *ip == *site {
+		return fmt.Errorf("must specify exactly one of --ip or --site")
+	}
+	if *input == "" {
+		return fmt.Errorf("-i input file is required")
+	}
+	if *outFormat != "text" && *outFormat != "json" && *outFormat != "yaml" {
+		return fmt.Errorf("--format must be 'text', 'json' or 'yaml'")
+	}
+
+	decoded, err := loadInput(*input, *site)
+	if err != nil {
+		return err
+	}
+	report := &validateReport{Findings: []geodata.Finding{}}
+	if *site {
+		report.Findings = append(report.Findings, geosite.Validate(decoded)...)
+	} else {
+		report.Findings = append(report.Findings, geoip.Validate(decoded)...)
+	}
+	for _, f := range report.Findings {
+		if f.Severity == geodata.SeverityError {
+			report.Errors++
+		} else {
+			report.Warnings++
+		}
+	}
+
+	if *outFormat == "text" {
+		if _, err := io.WriteString(stdout, formatValidateText(report)); err != nil {
+			return err
+		}
+	} else {
+		out, err := format.Marshal(report, *outFormat)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(stdout, string(out)); err != nil {
+			return err
+		}
+	}
+
+	if report.Errors > 0 || (*strict && report.Warnings > 0) {
+		return fmt.Errorf("validation failed: %d errors, %d warnings", report.Errors, report.Warnings)
+	}
+	return nil
+}
+
+// formatValidateText renders findings one per line followed by a summary.
+func formatValidateText(report *validateReport) string {
+	if len(report.Findings) == 0 {
+		return "✅ no problems found\n"
+	}
+	var b strings.Builder
+	for _, f := range report.Findings {
+		b.WriteString(f.String())
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, "%d errors, %d warnings\n", report.Errors, report.Warnings)
+	return b.String()
+}
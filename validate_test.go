@@ -0,0 +1,56 @@
+// validate_test.go
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunValidateErrors(t *testing.T) {
+	input := filepath.Join(t.TempDir(), "ip.json")
+	if err := os.WriteFile(input, []byte(`{"US": ["8.8.8.8/24", "8.8.8.0/33"]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	err := runValidate([]string{"--ip", "-i", input}, &out)
+	if err == nil || !strings.Contains(err.Error(), "1 errors, 1 warnings") {
+		t.Fatalf("expected validation failure, got %v", err)
+	}
+	expected := "warning: US[0] 8.8.8.8/24: host bits set (canonical form 8.8.8.0/24)\n" +
+		"error: US[1] 8.8.8.0/33: prefix length 33 exceeds 32\n" +
+		"1 errors, 1 warnings\n"
+	if out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+}
+
+func TestRunValidateStrict(t *testing.T) {
+	var out bytes.Buffer
+	if err := runValidate([]string{"--site", "-i", "example/zkeen-site.dat"}, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "duplicate of") {
+		t.Errorf("expected a duplicate warning, got %q", out.String())
+	}
+	out.Reset()
+	if err := runValidate([]string{"--site", "-i", "example/zkeen-site.dat", "--strict", "--format", "json"}, &out); err == nil {
+		t.Fatal("expected --strict to fail on warnings")
+	}
+	if !strings.Contains(out.String(), `"severity": "warning"`) {
+		t.Errorf("unexpected JSON output: %s", out.String())
+	}
+}
+
+func TestRunValidateClean(t *testing.T) {
+	var out bytes.Buffer
+	if err := runValidate([]string{"--ip", "-i", "example/zkeen-ip.dat"}, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != "✅ no problems found\n" {
+		t.Errorf("unexpected output: %q", out.String())
+	}
+}